@@ -0,0 +1,694 @@
+package conf
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/nuln/conf/internal/fields"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	structFieldsOf sync.Map // structKey -> []fields.Field
+)
+
+type structKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// structFields returns the fields of the struct type t as named for the
+// codec reading tag.
+func structFields(t reflect.Type, tag string) []fields.Field {
+	key := structKey{t, tag}
+	if fs, ok := structFieldsOf.Load(key); ok {
+		return fs.([]fields.Field)
+	}
+	fs, _ := structFieldsOf.LoadOrStore(key, fields.Of(t, tag, false))
+	return fs.([]fields.Field)
+}
+
+// bindable reports whether v is decoded and encoded by conf itself rather
+// than by the codec, which is the case for types using conf struct tags.
+func bindable(v any) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && fields.Tagged(t, "conf")
+}
+
+// KeyMatch selects how document keys are matched to struct fields.
+type KeyMatch uint8
+
+// Key matching modes.
+const (
+	// KeyExact matches keys that equal the field name. It is the default,
+	// under which structs without conf tags are decoded by the codec.
+	KeyExact KeyMatch = iota
+
+	// KeyFold matches keys that equal the field name ignoring case.
+	KeyFold
+
+	// KeyNormalized matches keys that equal the field name ignoring case,
+	// underscores, hyphens and spaces, so that max_conns, maxConns,
+	// max-conns and MaxConns all name the same field.
+	KeyNormalized
+)
+
+// WithKeyMatch makes Load, LoadFromBytes, LoadAll and LoadLayers match
+// document keys to struct fields with m, falling back to it when no field
+// is named by a key exactly. Since codecs differ in how they match keys,
+// structs are then decoded by conf itself, as for conf tags, so that every
+// format behaves the same.
+func WithKeyMatch(m KeyMatch) Option {
+	return func(o *options) {
+		o.keyMatch = m
+	}
+}
+
+// Decode decodes the tree n into v, which must be a non-nil pointer.
+// Struct fields are named by their conf tags, falling back to their json,
+// yaml and toml tags, as described for Load. Of the options, only
+// WithKeyMatch applies.
+func (n *Node) Decode(v any, opts ...Option) error {
+	return bindValue(n, v, newBinder("json", newOptions(opts)))
+}
+
+// bindValue decodes n into v with b.
+func bindValue(n *Node, v any, b *binder) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("conf: decode target must be a non-nil pointer, got %T", v)
+	}
+	return b.bind(nil, n, rv.Elem())
+}
+
+// unbindValue encodes v as a tree, naming fields for the codec reading
+// tag.
+func unbindValue(v any, tag string) (*Node, error) {
+	b := &binder{tag: tag}
+	return b.unbind(nil, reflect.ValueOf(v))
+}
+
+// binder converts between trees and Go values.
+type binder struct {
+	tag     string
+	match   KeyMatch
+	warn    func(Deprecation)
+	sources Metadata // if set, receives the sources of the bound values
+}
+
+// newBinder returns a binder naming fields for the codec reading tag and
+// configured by o.
+func newBinder(tag string, o *options) *binder {
+	return &binder{tag: tag, match: o.keyMatch, warn: o.deprecation}
+}
+
+// binds reports whether v is decoded by conf itself rather than by the
+// codec under o, which is the case for types using conf struct tags and
+// for all types under WithKeyMatch.
+func (o *options) binds(v any) bool {
+	if _, ok := v.(*Node); ok {
+		return false
+	}
+	return reflect.TypeOf(v) != nil && (bindable(v) || o.keyMatch != KeyExact)
+}
+
+// bind decodes n into v; path is the location of n, for errors.
+func (b *binder) bind(path []pathElem, n *Node, v reflect.Value) error {
+	if !hasChildren(n) {
+		b.record(path, n)
+	}
+	if n.Kind == NullNode {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			v.SetZero()
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return b.bind(path, n, v.Elem())
+	}
+	if hook := hookFor(v.Type()); hook != nil {
+		b.record(path, n)
+		x, err := hook(n)
+		if err != nil {
+			return b.wrap(path, n, err)
+		}
+		v.Set(x)
+		return nil
+	}
+	if v.Type() == timeType {
+		return b.bindTime(path, n, v)
+	}
+	if v.CanAddr() {
+		switch u := v.Addr().Interface().(type) {
+		case json.Unmarshaler:
+			b.record(path, n)
+			data, err := n.MarshalJSON()
+			if err == nil {
+				err = u.UnmarshalJSON(data)
+			}
+			return b.wrap(path, n, err)
+		case encoding.TextUnmarshaler:
+			if n.Kind != StringNode {
+				return b.mismatch(path, n, v.Type())
+			}
+			return b.wrap(path, n, u.UnmarshalText([]byte(n.Value.(string))))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return b.mismatch(path, n, v.Type())
+		}
+		b.record(path, n)
+		v.Set(reflect.ValueOf(n.Interface()))
+	case reflect.Bool:
+		switch {
+		case n.Kind == BoolNode:
+			v.SetBool(n.Value.(bool))
+		case n.Kind == StringNode:
+			x, err := strconv.ParseBool(n.Value.(string))
+			if err != nil {
+				return b.mismatch(path, n, v.Type())
+			}
+			v.SetBool(x)
+		default:
+			return b.mismatch(path, n, v.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, ok := intValue(n)
+		if !ok || v.OverflowInt(x) {
+			return b.mismatch(path, n, v.Type())
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, ok := intValue(n)
+		if !ok || x < 0 || v.OverflowUint(uint64(x)) {
+			return b.mismatch(path, n, v.Type())
+		}
+		v.SetUint(uint64(x))
+	case reflect.Float32, reflect.Float64:
+		x, ok := floatValue(n)
+		if !ok || v.OverflowFloat(x) {
+			return b.mismatch(path, n, v.Type())
+		}
+		v.SetFloat(x)
+	case reflect.String:
+		if n.Kind != StringNode {
+			return b.mismatch(path, n, v.Type())
+		}
+		v.SetString(n.Value.(string))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && n.Kind != ListNode {
+			return b.bindBytes(path, n, v)
+		}
+		items := listItems(n)
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := b.bind(append(path[:len(path):len(path)], pathElem{index: i}), item, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		items := listItems(n)
+		v.SetZero()
+		for i, item := range items[:min(len(items), v.Len())] {
+			if err := b.bind(append(path[:len(path):len(path)], pathElem{index: i}), item, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return b.bindMap(path, n, v)
+	case reflect.Struct:
+		return b.bindStruct(path, n, v)
+	default:
+		return fmt.Errorf("conf: %s: unsupported type %s", where(path), v.Type())
+	}
+	return nil
+}
+
+// record adds the sources of n, bound at path, and of the values below it
+// to b.sources. Struct fields are named as in the struct, not the document.
+func (b *binder) record(path []pathElem, n *Node) {
+	if b.sources == nil {
+		return
+	}
+	names := make([]pathElem, len(path))
+	for i, e := range path {
+		if e.field != "" {
+			e.key = e.field
+		}
+		names[i] = e
+	}
+	collectSources(b.sources, names, n)
+}
+
+func (b *binder) bindTime(path []pathElem, n *Node, v reflect.Value) error {
+	switch n.Kind {
+	case TimeNode:
+		v.Set(reflect.ValueOf(n.Value))
+		return nil
+	case StringNode:
+		t, err := time.Parse(time.RFC3339Nano, n.Value.(string))
+		if err != nil {
+			return b.wrap(path, n, err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	return b.mismatch(path, n, v.Type())
+}
+
+// bindBytes decodes a binary value, or a base64 string as encoding/json
+// writes []byte, into v.
+func (b *binder) bindBytes(path []pathElem, n *Node, v reflect.Value) error {
+	var data []byte
+	switch n.Kind {
+	case BytesNode:
+		data = n.Value.([]byte)
+	case StringNode:
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(n.Value.(string)); err != nil {
+			return b.wrap(path, n, err)
+		}
+	default:
+		return b.mismatch(path, n, v.Type())
+	}
+	v.SetBytes(append([]byte(nil), data...))
+	return nil
+}
+
+func (b *binder) bindMap(path []pathElem, n *Node, v reflect.Value) error {
+	if n.Kind != MapNode {
+		return b.mismatch(path, n, v.Type())
+	}
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(n.Fields)))
+	}
+	for _, f := range n.Fields {
+		at := append(path[:len(path):len(path)], pathElem{key: f.Key, index: -1})
+		key := reflect.New(t.Key()).Elem()
+		if err := b.bind(at, &Node{Kind: StringNode, Value: f.Key}, key); err != nil {
+			return err
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := b.bind(at, f.Value, elem); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func (b *binder) bindStruct(path []pathElem, n *Node, v reflect.Value) error {
+	if n.Kind != MapNode {
+		return b.mismatch(path, n, v.Type())
+	}
+	fs := structFields(v.Type(), b.tag)
+	type match struct {
+		i     int
+		alias bool
+	}
+	// A field's own name takes precedence over its aliases.
+	matches := make([]match, len(n.Fields))
+	named := make([]bool, len(fs))
+	for j, f := range n.Fields {
+		i, alias := b.field(fs, f.Key)
+		matches[j] = match{i, alias}
+		if i >= 0 && !alias {
+			named[i] = true
+		}
+	}
+	set := make([]bool, len(fs))
+	for j, f := range n.Fields {
+		i, alias := matches[j].i, matches[j].alias
+		if i < 0 {
+			continue
+		}
+		b.deprecated(path, fs[i], f, alias)
+		if alias && named[i] {
+			continue
+		}
+		fv, err := fieldByIndex(v, fs[i].Index)
+		if err != nil {
+			return fmt.Errorf("conf: %s: %w", where(path), err)
+		}
+		at := append(path[:len(path):len(path)], pathElem{key: f.Key, index: -1, field: fs[i].Keys[0]})
+		if err := b.bind(at, f.Value, fv); err != nil {
+			return err
+		}
+		set[i] = f.Value.Kind != NullNode
+	}
+	for i, sf := range fs {
+		if sf.Has("required") && !set[i] {
+			at := append(path[:len(path):len(path)], pathElem{key: sf.Keys[0], index: -1})
+			return fmt.Errorf("%w: %s", ErrMissingField, formatPath(at))
+		}
+	}
+	return nil
+}
+
+// field returns the index of the field in fs named by key, or -1, and
+// whether key is one of its aliases. Exact matches take precedence over
+// those under b.match. Fields without a tag naming them match keys ignoring
+// case, as most codecs do.
+func (b *binder) field(fs []fields.Field, key string) (int, bool) {
+	for i, f := range fs {
+		if f.Keys[0] == key {
+			return i, false
+		}
+	}
+	for i, f := range fs {
+		if slices.Contains(f.Values("alias"), key) {
+			return i, true
+		}
+	}
+	for i, f := range fs {
+		if f.Implicit && strings.EqualFold(f.Keys[0], key) || b.matches(f.Keys[0], key) {
+			return i, false
+		}
+	}
+	for i, f := range fs {
+		for _, alias := range f.Values("alias") {
+			if b.matches(alias, key) {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// matches reports whether key names name under b.match.
+func (b *binder) matches(name, key string) bool {
+	switch b.match {
+	case KeyFold:
+		return strings.EqualFold(name, key)
+	case KeyNormalized:
+		return normalizeKey(name) == normalizeKey(key)
+	}
+	return false
+}
+
+// deprecated reports the field f of the struct at path to b.warn if it was
+// set through a deprecated key: one of its aliases, or its own name if it
+// has none.
+func (b *binder) deprecated(path []pathElem, f fields.Field, kv Field, alias bool) {
+	msgs := f.Values("deprecated")
+	if b.warn == nil || len(msgs) == 0 && !f.Has("deprecated") || !alias && len(f.Values("alias")) > 0 {
+		return
+	}
+	d := Deprecation{
+		Path: formatPath(append(path[:len(path):len(path)], pathElem{key: kv.Key, index: -1})),
+		Line: kv.Value.Line,
+	}
+	if alias {
+		d.Replacement = formatPath(append(path[:len(path):len(path)], pathElem{key: f.Keys[0], index: -1}))
+	}
+	if len(msgs) > 0 {
+		d.Message = msgs[0]
+	}
+	b.warn(d)
+}
+
+// normalizeKey lower-cases key and drops underscores, hyphens and spaces.
+func normalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ' ':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, key)
+}
+
+// unbind encodes v as a tree; path is the location of v, for errors.
+func (b *binder) unbind(path []pathElem, v reflect.Value) (*Node, error) {
+	if !v.IsValid() {
+		return &Node{Kind: NullNode}, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return &Node{Kind: NullNode}, nil
+		}
+		if v.Kind() == reflect.Interface {
+			return b.unbind(path, v.Elem())
+		}
+	}
+	if v.Type() == timeType {
+		return &Node{Kind: TimeNode, Value: v.Interface()}, nil
+	}
+	if n, ok, err := b.marshal(path, v); ok {
+		return n, err
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return b.unbind(path, v.Elem())
+	case reflect.Bool:
+		return &Node{Kind: BoolNode, Value: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Node{Kind: IntNode, Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u <= math.MaxInt64 {
+			return &Node{Kind: IntNode, Value: int64(u)}, nil
+		}
+		return &Node{Kind: FloatNode, Value: float64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Node{Kind: FloatNode, Value: v.Float()}, nil
+	case reflect.String:
+		return &Node{Kind: StringNode, Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &Node{Kind: NullNode}, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return &Node{Kind: BytesNode, Value: data}, nil
+		}
+		n := &Node{Kind: ListNode, Items: make([]*Node, v.Len())}
+		for i := range n.Items {
+			item, err := b.unbind(append(path[:len(path):len(path)], pathElem{index: i}), v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			n.Items[i] = item
+		}
+		return n, nil
+	case reflect.Map:
+		return b.unbindMap(path, v)
+	case reflect.Struct:
+		return b.unbindStruct(path, v)
+	}
+	return nil, fmt.Errorf("conf: %s: unsupported type %s", where(path), v.Type())
+}
+
+// marshal encodes v with its MarshalJSON or MarshalText method, reporting
+// false if it has neither.
+func (b *binder) marshal(path []pathElem, v reflect.Value) (*Node, bool, error) {
+	if !v.CanInterface() {
+		return nil, false, nil
+	}
+	m := v.Interface()
+	if v.Kind() != reflect.Pointer && v.CanAddr() {
+		m = v.Addr().Interface()
+	}
+	switch m := m.(type) {
+	case json.Marshaler:
+		data, err := m.MarshalJSON()
+		if err != nil {
+			return nil, true, fmt.Errorf("conf: %s: %w", where(path), err)
+		}
+		var n Node
+		if err := n.UnmarshalJSON(data); err != nil {
+			return nil, true, fmt.Errorf("conf: %s: %w", where(path), err)
+		}
+		return &n, true, nil
+	case encoding.TextMarshaler:
+		text, err := m.MarshalText()
+		if err != nil {
+			return nil, true, fmt.Errorf("conf: %s: %w", where(path), err)
+		}
+		return &Node{Kind: StringNode, Value: string(text)}, true, nil
+	}
+	return nil, false, nil
+}
+
+func (b *binder) unbindMap(path []pathElem, v reflect.Value) (*Node, error) {
+	if v.IsNil() {
+		return &Node{Kind: NullNode}, nil
+	}
+	n := &Node{Kind: MapNode, Fields: make([]Field, 0, v.Len())}
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := b.unbind(path, iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprint(key.Value)
+		value, err := b.unbind(append(path[:len(path):len(path)], pathElem{key: name, index: -1}), iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		n.Fields = append(n.Fields, Field{Key: name, Value: value})
+	}
+	sort.Slice(n.Fields, func(i, j int) bool { return n.Fields[i].Key < n.Fields[j].Key })
+	return n, nil
+}
+
+func (b *binder) unbindStruct(path []pathElem, v reflect.Value) (*Node, error) {
+	fs := structFields(v.Type(), b.tag)
+	n := &Node{Kind: MapNode, Fields: make([]Field, 0, len(fs))}
+	for _, f := range fs {
+		fv, ok := lookupField(v, f.Index)
+		if !ok || isNil(fv) || f.Has("omitempty") && isEmpty(fv) {
+			continue
+		}
+		at := append(path[:len(path):len(path)], pathElem{key: f.Keys[0], index: -1})
+		value, err := b.unbind(at, fv)
+		if err != nil {
+			return nil, err
+		}
+		n.Fields = append(n.Fields, Field{Key: f.Keys[0], Value: value})
+	}
+	return n, nil
+}
+
+// fieldByIndex returns the field at index within v, allocating nil
+// pointers to embedded structs along the way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// lookupField returns the field at index within v, reporting false if a
+// nil embedded pointer is in the way.
+func lookupField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isNil reports whether v is a nil pointer, interface, map or slice. Such
+// fields are left out when encoding, since not every format can write
+// null.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isEmpty reports whether v is omitted by omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// listItems returns the elements of a list, or n itself as the only
+// element, since formats such as XML cannot tell a single element from a
+// list of one.
+func listItems(n *Node) []*Node {
+	if n.Kind == ListNode {
+		return n.Items
+	}
+	return []*Node{n}
+}
+
+// intValue returns n as an integer. Integral floats and strings holding an
+// integer, as produced by formats such as XML, are accepted.
+func intValue(n *Node) (int64, bool) {
+	switch n.Kind {
+	case IntNode:
+		return n.Value.(int64), true
+	case FloatNode:
+		if f := n.Value.(float64); f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return int64(f), true
+		}
+	case StringNode:
+		i, err := strconv.ParseInt(n.Value.(string), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// floatValue returns n as a number. Strings holding a number are accepted.
+func floatValue(n *Node) (float64, bool) {
+	switch n.Kind {
+	case FloatNode:
+		return n.Value.(float64), true
+	case IntNode:
+		return float64(n.Value.(int64)), true
+	case StringNode:
+		f, err := strconv.ParseFloat(n.Value.(string), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// where describes path for errors.
+func where(path []pathElem) string {
+	if len(path) == 0 {
+		return "document root"
+	}
+	return formatPath(path)
+}
+
+func (b *binder) mismatch(path []pathElem, n *Node, t reflect.Type) error {
+	if n.Line > 0 {
+		return fmt.Errorf("%w: %s (line %d): want %s, got %s", ErrTypeMismatch, where(path), n.Line, t, n.Kind)
+	}
+	return fmt.Errorf("%w: %s: want %s, got %s", ErrTypeMismatch, where(path), t, n.Kind)
+}
+
+func (b *binder) wrap(path []pathElem, n *Node, err error) error {
+	if err == nil {
+		return nil
+	}
+	if n.Line > 0 {
+		return fmt.Errorf("conf: %s (line %d): %w", where(path), n.Line, err)
+	}
+	return fmt.Errorf("conf: %s: %w", where(path), err)
+}
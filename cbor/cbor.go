@@ -0,0 +1,66 @@
+// Package cbor provides a CBOR (RFC 8949) codec for the conf package.
+// Import this package to register the "cbor" codec:
+//
+//	import _ "github.com/nuln/conf/cbor"
+//
+// Struct fields are named by their cbor tag, falling back to the json tag.
+// Encoding uses the deterministic core encoding, so equal values always
+// produce identical bytes. Types implementing encoding.TextMarshaler, such
+// as conf.Duration, are written as text strings, as in the text formats.
+package cbor
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.Register("cbor", New())
+}
+
+var encMode, decMode = modes()
+
+// modes returns the encoding and decoding modes shared by all codecs. The
+// options are fixed, so an error is a programming mistake.
+func modes() (cbor.EncMode, cbor.DecMode) {
+	encOpts := cbor.CoreDetEncOptions()
+	encOpts.TextMarshaler = cbor.TextMarshalerTextString
+	em, err := encOpts.EncMode()
+	if err != nil {
+		panic("conf/cbor: invalid encoding options: " + err.Error())
+	}
+	// Maps decoded into interface values use string keys, matching the
+	// text codecs.
+	dm, err := cbor.DecOptions{
+		DefaultMapType:  reflect.TypeOf(map[string]any(nil)),
+		TextUnmarshaler: cbor.TextUnmarshalerTextString,
+	}.DecMode()
+	if err != nil {
+		panic("conf/cbor: invalid decoding options: " + err.Error())
+	}
+	return em, dm
+}
+
+// New returns a new CBOR codec.
+func New() conf.Codec {
+	return &cborCodec{}
+}
+
+type cborCodec struct{}
+
+func (c *cborCodec) Encode(v any) ([]byte, error) {
+	return encMode.Marshal(v)
+}
+
+func (c *cborCodec) Decode(data []byte, v any) error {
+	return decMode.Unmarshal(data, v)
+}
+
+func (c *cborCodec) Extensions() []string {
+	return []string{".cbor"}
+}
+
+var _ conf.Codec = (*cborCodec)(nil)
@@ -0,0 +1,73 @@
+package cbor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuln/conf"
+	cc "github.com/nuln/conf/cbor"
+	"github.com/nuln/conf/conftest"
+)
+
+func TestCBOR(t *testing.T) {
+	conftest.Suite(t, cc.New())
+}
+
+func TestCBORRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "cbor" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("cbor should be registered via init()")
+	}
+}
+
+func TestCBORBytesRoundTrip(t *testing.T) {
+	original := map[string]any{"name": "edge", "port": 8080}
+
+	data, err := conf.SaveToBytes(original, "cbor")
+	if err != nil {
+		t.Fatalf("SaveToBytes failed: %v", err)
+	}
+
+	var loaded map[string]any
+	if err := conf.LoadFromBytes(data, "cbor", &loaded); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if loaded["name"] != "edge" {
+		t.Errorf("name: got %#v", loaded["name"])
+	}
+}
+
+func TestCBORTextMarshalers(t *testing.T) {
+	type limits struct {
+		Timeout conf.Duration `json:"timeout"`
+		MaxBody conf.ByteSize `json:"max_body"`
+	}
+	original := limits{Timeout: conf.Duration(time.Minute), MaxBody: 512 * conf.MiB}
+
+	data, err := conf.SaveToBytes(original, "cbor")
+	if err != nil {
+		t.Fatalf("SaveToBytes failed: %v", err)
+	}
+	var doc map[string]any
+	if err := conf.LoadFromBytes(data, "cbor", &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["timeout"] != "1m" || doc["max_body"] != "512MiB" {
+		t.Errorf("want text strings, got %#v", doc)
+	}
+
+	var loaded limits
+	if err := conf.LoadFromBytes(data, "cbor", &loaded); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if loaded != original {
+		t.Errorf("got %+v, want %+v", loaded, original)
+	}
+}
@@ -2,6 +2,10 @@ package conf
 
 // Codec defines the unified interface for configuration encoding and decoding.
 // All adapter implementations must satisfy this interface.
+//
+// Codecs name struct fields by their own tags only. conf struct tags, key
+// matching and decode hooks are applied by the package functions such as
+// Load and Save, which decode and encode such structs themselves.
 type Codec interface {
 	// Encode serializes the given value into bytes.
 	// v should be a value (struct, map, etc.) to encode.
@@ -15,3 +19,24 @@ type Codec interface {
 	// including the leading dot (e.g. [".json"]).
 	Extensions() []string
 }
+
+// FileCodec is an optional interface implemented by codecs whose decoding
+// depends on the location of the file being decoded, for example to
+// resolve imports relative to it. Load, LoadAll, LoadLayers, Edit and
+// ConvertFile decode files with the codec returned by ForFile.
+type FileCodec interface {
+	// ForFile returns a codec that decodes the contents of the file at
+	// path.
+	ForFile(path string) Codec
+}
+
+// forFile returns the codec that decodes the file at path with codec.
+func forFile(codec Codec, path string) Codec {
+	switch c := codec.(type) {
+	case *compressedCodec:
+		return &compressedCodec{codec: forFile(c.codec, path), compressor: c.compressor}
+	case FileCodec:
+		return c.ForFile(path)
+	}
+	return codec
+}
@@ -0,0 +1,134 @@
+package conf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Compressor defines a compression format that can wrap any codec.
+// Files whose name ends in one of its extensions after a codec extension
+// (e.g. "config.json.gz") are decompressed on Load and compressed on Save.
+type Compressor interface {
+	// Compress returns the compressed form of data.
+	Compress(data []byte) ([]byte, error)
+
+	// Decompress returns the original form of compressed data.
+	Decompress(data []byte) ([]byte, error)
+
+	// Extensions returns the file extensions this compressor handles,
+	// including the leading dot (e.g. [".gz"]).
+	Extensions() []string
+}
+
+var (
+	compressors = make(map[string]Compressor)
+	// compressorExt maps file extensions (e.g. ".gz") to compressor names.
+	compressorExt = make(map[string]string)
+)
+
+// RegisterCompressor registers a compressor under the given name.
+// Compression sub-packages call this in their init() functions.
+func RegisterCompressor(name string, c Compressor) {
+	mu.Lock()
+	defer mu.Unlock()
+	compressors[name] = c
+	for _, ext := range c.Extensions() {
+		compressorExt[ext] = name
+	}
+}
+
+// GetCompressor returns the compressor registered under the given name.
+// Returns nil if not found.
+func GetCompressor(name string) Compressor {
+	mu.RLock()
+	defer mu.RUnlock()
+	return compressors[name]
+}
+
+// AvailableCompressors returns a sorted list of registered compressor names.
+func AvailableCompressors() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(compressors))
+	for name := range compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compressorByExt returns the compressor registered for the given file
+// extension, or nil.
+func compressorByExt(ext string) Compressor {
+	mu.RLock()
+	defer mu.RUnlock()
+	return compressors[compressorExt[ext]]
+}
+
+// compressedCodec applies a compressor around a codec.
+type compressedCodec struct {
+	codec      Codec
+	compressor Compressor
+}
+
+func (c *compressedCodec) Encode(v any) ([]byte, error) {
+	data, err := c.codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.compressor.Compress(data)
+}
+
+func (c *compressedCodec) Decode(data []byte, v any) error {
+	data, err := c.compressor.Decompress(data)
+	if err != nil {
+		return fmt.Errorf("decompressing: %w", err)
+	}
+	return c.codec.Decode(data, v)
+}
+
+func (c *compressedCodec) DecodeAll(data []byte, fn func(decode func(v any) error) error) error {
+	data, err := c.compressor.Decompress(data)
+	if err != nil {
+		return fmt.Errorf("decompressing: %w", err)
+	}
+	if md, ok := c.codec.(MultiDecoder); ok {
+		return md.DecodeAll(data, fn)
+	}
+	return fn(func(v any) error {
+		return c.codec.Decode(data, v)
+	})
+}
+
+func (c *compressedCodec) DecodeNode(data []byte) (*Node, error) {
+	data, err := c.compressor.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing: %w", err)
+	}
+	return decodeNode(c.codec, data)
+}
+
+func (c *compressedCodec) EncodeNode(n *Node) ([]byte, error) {
+	data, err := encodeNode(c.codec, n)
+	if err != nil {
+		return nil, err
+	}
+	return c.compressor.Compress(data)
+}
+
+func (c *compressedCodec) Extensions() []string {
+	var exts []string
+	for _, inner := range c.codec.Extensions() {
+		for _, outer := range c.compressor.Extensions() {
+			exts = append(exts, inner+outer)
+		}
+	}
+	return exts
+}
+
+var (
+	_ Codec        = (*compressedCodec)(nil)
+	_ MultiDecoder = (*compressedCodec)(nil)
+	_ NodeCodec    = (*compressedCodec)(nil)
+)
@@ -0,0 +1,56 @@
+// Package bzip2 provides a bzip2 compressor for the conf package.
+// Import this package to register the "bzip2" compressor, so that files
+// such as "config.toml.bz2" are transparently decompressed and compressed:
+//
+//	import _ "github.com/nuln/conf/compress/bzip2"
+package bzip2
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/dsnet/compress/bzip2"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.RegisterCompressor("bzip2", New())
+}
+
+// New returns a new bzip2 compressor.
+func New() conf.Compressor {
+	return &bzip2Compressor{}
+}
+
+type bzip2Compressor struct{}
+
+func (c *bzip2Compressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := bzip2.NewWriter(&buf, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *bzip2Compressor) Decompress(data []byte) ([]byte, error) {
+	r, err := bzip2.NewReader(bytes.NewReader(data), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (c *bzip2Compressor) Extensions() []string {
+	return []string{".bz2"}
+}
+
+var _ conf.Compressor = (*bzip2Compressor)(nil)
@@ -0,0 +1,19 @@
+package bzip2_test
+
+import (
+	"testing"
+
+	"github.com/nuln/conf"
+	cb "github.com/nuln/conf/compress/bzip2"
+	"github.com/nuln/conf/conftest"
+)
+
+func TestBzip2(t *testing.T) {
+	conftest.CompressorSuite(t, cb.New())
+}
+
+func TestBzip2Registration(t *testing.T) {
+	if conf.GetCompressor("bzip2") == nil {
+		t.Error("bzip2 should be registered via init()")
+	}
+}
@@ -0,0 +1,52 @@
+// Package gzip provides a gzip compressor for the conf package.
+// Import this package to register the "gzip" compressor, so that files
+// such as "config.json.gz" are transparently decompressed and compressed:
+//
+//	import _ "github.com/nuln/conf/compress/gzip"
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.RegisterCompressor("gzip", New())
+}
+
+// New returns a new gzip compressor.
+func New() conf.Compressor {
+	return &gzipCompressor{}
+}
+
+type gzipCompressor struct{}
+
+func (c *gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (c *gzipCompressor) Extensions() []string {
+	return []string{".gz"}
+}
+
+var _ conf.Compressor = (*gzipCompressor)(nil)
@@ -0,0 +1,19 @@
+package gzip_test
+
+import (
+	"testing"
+
+	"github.com/nuln/conf"
+	cg "github.com/nuln/conf/compress/gzip"
+	"github.com/nuln/conf/conftest"
+)
+
+func TestGzip(t *testing.T) {
+	conftest.CompressorSuite(t, cg.New())
+}
+
+func TestGzipRegistration(t *testing.T) {
+	if conf.GetCompressor("gzip") == nil {
+		t.Error("gzip should be registered via init()")
+	}
+}
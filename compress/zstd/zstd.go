@@ -0,0 +1,55 @@
+// Package zstd provides a Zstandard compressor for the conf package.
+// Import this package to register the "zstd" compressor, so that files
+// such as "config.yaml.zst" are transparently decompressed and compressed:
+//
+//	import _ "github.com/nuln/conf/compress/zstd"
+package zstd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.RegisterCompressor("zstd", New())
+}
+
+// Encoder and decoder are safe for concurrent use through EncodeAll and
+// DecodeAll, so they are created once and shared by all calls.
+var (
+	encoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	decoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+// New returns a new Zstandard compressor.
+func New() conf.Compressor {
+	return &zstdCompressor{}
+}
+
+type zstdCompressor struct{}
+
+func (c *zstdCompressor) Compress(data []byte) ([]byte, error) {
+	enc, err := encoder()
+	if err != nil {
+		return nil, fmt.Errorf("zstd: creating encoder: %w", err)
+	}
+	return enc.EncodeAll(data, nil), nil
+}
+
+func (c *zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	dec, err := decoder()
+	if err != nil {
+		return nil, fmt.Errorf("zstd: creating decoder: %w", err)
+	}
+	return dec.DecodeAll(data, nil)
+}
+
+func (c *zstdCompressor) Extensions() []string {
+	return []string{".zst"}
+}
+
+var _ conf.Compressor = (*zstdCompressor)(nil)
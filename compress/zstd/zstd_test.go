@@ -0,0 +1,19 @@
+package zstd_test
+
+import (
+	"testing"
+
+	"github.com/nuln/conf"
+	cz "github.com/nuln/conf/compress/zstd"
+	"github.com/nuln/conf/conftest"
+)
+
+func TestZstd(t *testing.T) {
+	conftest.CompressorSuite(t, cz.New())
+}
+
+func TestZstdRegistration(t *testing.T) {
+	if conf.GetCompressor("zstd") == nil {
+		t.Error("zstd should be registered via init()")
+	}
+}
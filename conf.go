@@ -4,41 +4,48 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Load reads the file at path, detects the format from the file extension,
-// and decodes its contents into v. v must be a pointer.
-func Load(path string, v any) error {
-	codec, err := codecForPath(path)
+// and decodes its contents into v. v must be a pointer; a *Node receives
+// the document tree with its key order and source positions.
+// If the extension is missing or unregistered, the format is detected from
+// the content using codecs that implement Sniffer. Use WithFormat to force
+// a codec regardless of the file name.
+func Load(path string, v any, opts ...Option) error {
+	o := newOptions(opts)
+	codec, data, err := readFile(path, o)
 	if err != nil {
 		return err
 	}
 
-	data, err := os.ReadFile(path) //nolint:gosec // path is user-provided by design
-	if err != nil {
-		return fmt.Errorf("conf: reading %s: %w", path, err)
-	}
-
-	if err := codec.Decode(data, v); err != nil {
+	if err := decodeFile(codec, data, path, v, o); err != nil {
 		return fmt.Errorf("conf: decoding %s: %w", path, err)
 	}
 	return nil
 }
 
-// Save encodes v and writes the result to the file at path.
-// The format is detected from the file extension.
-// Parent directories are created automatically if they do not exist.
-func Save(path string, v any) error {
-	codec, err := codecForPath(path)
+// Save encodes v, which may be a *Node, and writes the result to the file
+// at path. The format is detected from the file extension unless forced with
+// WithFormat. Parent directories are created automatically if they do not
+// exist.
+func Save(path string, v any, opts ...Option) error {
+	codec, err := newOptions(opts).codecFor(path)
 	if err != nil {
 		return err
 	}
 
-	data, err := codec.Encode(v)
+	data, err := encode(codec, v)
 	if err != nil {
 		return fmt.Errorf("conf: encoding %s: %w", path, err)
 	}
+	return writeFile(path, data)
+}
 
+// writeFile writes data to the file at path, creating parent directories
+// as needed.
+func writeFile(path string, data []byte) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o750); err != nil {
 		return fmt.Errorf("conf: creating directory %s: %w", dir, err)
@@ -52,12 +59,14 @@ func Save(path string, v any) error {
 
 // LoadFromBytes decodes data in the named format into v.
 // format is a registered codec name (e.g. "json", "yaml", "toml").
-func LoadFromBytes(data []byte, format string, v any) error {
+// Options that concern files, such as WithFormat, are ignored.
+func LoadFromBytes(data []byte, format string, v any, opts ...Option) error {
 	codec := Get(format)
 	if codec == nil {
 		return fmt.Errorf("%w: %q (available: %v)", ErrUnsupportedFormat, format, Available())
 	}
-	if err := codec.Decode(data, v); err != nil {
+	o := newOptions(opts)
+	if err := decodeFile(codec, data, "", v, o); err != nil {
 		return fmt.Errorf("conf: decoding %s: %w", format, err)
 	}
 	return nil
@@ -70,7 +79,7 @@ func SaveToBytes(v any, format string) ([]byte, error) {
 	if codec == nil {
 		return nil, fmt.Errorf("%w: %q (available: %v)", ErrUnsupportedFormat, format, Available())
 	}
-	data, err := codec.Encode(v)
+	data, err := encode(codec, v)
 	if err != nil {
 		return nil, fmt.Errorf("conf: encoding %s: %w", format, err)
 	}
@@ -78,10 +87,19 @@ func SaveToBytes(v any, format string) ([]byte, error) {
 }
 
 // codecForPath returns the codec matched by the file extension of path.
+// A compressor extension such as ".gz" wraps the codec matched by the
+// extension before it, so "config.json.gz" yields compressed JSON.
 func codecForPath(path string) (Codec, error) {
 	ext := filepath.Ext(path)
 	if ext == "" {
 		return nil, fmt.Errorf("%w: file %q has no extension", ErrUnsupportedFormat, path)
 	}
+	if compressor := compressorByExt(ext); compressor != nil {
+		codec, err := codecForPath(strings.TrimSuffix(path, ext))
+		if err != nil {
+			return nil, err
+		}
+		return &compressedCodec{codec: codec, compressor: compressor}, nil
+	}
 	return codecByExt(ext)
 }
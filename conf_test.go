@@ -2,12 +2,21 @@ package conf_test
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nuln/conf"
-	_ "github.com/nuln/conf/json"
+	_ "github.com/nuln/conf/compress/gzip"
+	cj "github.com/nuln/conf/json"
 	_ "github.com/nuln/conf/toml"
 	_ "github.com/nuln/conf/yaml"
 )
@@ -205,3 +214,1339 @@ func TestGet(t *testing.T) {
 		t.Error("Get(\"nonexistent\") should return nil")
 	}
 }
+
+func TestRegisterNamed(t *testing.T) {
+	conf.RegisterNamed("json-compact", cj.New(cj.Compact()))
+
+	data, err := conf.SaveToBytes(map[string]int{"port": 8080}, "json-compact")
+	if err != nil {
+		t.Fatalf("SaveToBytes failed: %v", err)
+	}
+	if string(data) != `{"port":8080}` {
+		t.Errorf("got %q", data)
+	}
+
+	// The default json codec must still own the .json extension.
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := conf.Save(path, map[string]int{"port": 8080}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) == string(data) {
+		t.Error("RegisterNamed should not claim the .json extension")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	input := "# application name\nname: myapp\nport: 8080 # listen port\n"
+	if err := os.WriteFile(path, []byte(input), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := conf.Edit(path, func(doc *conf.Document) error {
+		var port int
+		if err := doc.Get("port", &port); err != nil {
+			return err
+		}
+		if err := doc.Set("port", port+1); err != nil {
+			return err
+		}
+		return doc.Set("database.host", "localhost")
+	})
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# application name\nname: myapp\nport: 8081 # listen port\ndatabase:\n    host: localhost\n"
+	if string(data) != want {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestEditKeyNotFound(t *testing.T) {
+	_, err := conf.EditBytes([]byte(`{"name": "myapp"}`), "json", func(doc *conf.Document) error {
+		return doc.Delete("database.host")
+	})
+	if !errors.Is(err, conf.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got: %v", err)
+	}
+}
+
+func TestEditCallbackError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	input := "name = \"myapp\"\n"
+	if err := os.WriteFile(path, []byte(input), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	errAbort := errors.New("abort")
+	err := conf.Edit(path, func(doc *conf.Document) error {
+		if err := doc.Set("name", "other"); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected callback error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != input {
+		t.Errorf("file modified despite error: %q", data)
+	}
+}
+
+func TestEditReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("labels:\n    team: core\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	err := conf.Edit(path, func(doc *conf.Document) error {
+		return doc.Set(`labels["app.kubernetes.io/name"]`, "web")
+	})
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "labels:\n    team: core\n    app.kubernetes.io/name: web\n"; string(data) != want {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", data, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestEditRoot(t *testing.T) {
+	inputs := map[string]string{
+		"json": `{"name": "myapp"}`,
+		"yaml": "name: myapp\n",
+		"toml": "name = \"myapp\"\n",
+	}
+	for format, input := range inputs {
+		t.Run(format, func(t *testing.T) {
+			_, err := conf.EditBytes([]byte(input), format, func(doc *conf.Document) error {
+				var v map[string]any
+				if err := doc.Get("", &v); err != nil {
+					return err
+				}
+				if v["name"] != "myapp" {
+					t.Errorf("Get(\"\") = %v", v)
+				}
+				return doc.Set("", map[string]any{"name": "other"})
+			})
+			if err == nil || !strings.Contains(err.Error(), "cannot replace the document root") {
+				t.Errorf("Set(\"\") error = %v", err)
+			}
+
+			_, err = conf.EditBytes([]byte(input), format, func(doc *conf.Document) error {
+				return doc.Set("servers[0]", "a")
+			})
+			if err == nil {
+				t.Error("Set with a list index succeeded")
+			}
+		})
+	}
+}
+
+func TestLoadAllSingleDocument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := sampleConfig()
+	if err := conf.Save(path, &original); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	calls := 0
+	err := conf.LoadAll(path, func(i int, decode func(v any) error) error {
+		calls++
+		var loaded appConfig
+		if err := decode(&loaded); err != nil {
+			return err
+		}
+		if loaded.Name != original.Name {
+			t.Errorf("Name: got %q, want %q", loaded.Name, original.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 document, got %d", calls)
+	}
+}
+
+func TestLoadSaveCompressed(t *testing.T) {
+	for _, ext := range []string{".json.gz", ".toml.gz", ".yaml.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config"+ext)
+			original := sampleConfig()
+
+			if err := conf.Save(path, &original); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+				t.Errorf("expected gzip data, got %q", data)
+			}
+
+			var loaded appConfig
+			if err := conf.Load(path, &loaded); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if loaded != original {
+				t.Errorf("got %+v, want %+v", loaded, original)
+			}
+		})
+	}
+}
+
+func TestLoadCompressedWithoutCodecExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.gz")
+	if err := os.WriteFile(path, []byte("\x1f\x8b"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var cfg appConfig
+	err := conf.Load(path, &cfg)
+	if !errors.Is(err, conf.ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got: %v", err)
+	}
+}
+
+func TestLoadDetectsFormatFromContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config", "# app settings\nname: myapp\nport: 8080\n"},
+		{"settings.conf", "name = \"myapp\"\nport = 8080\n\n[database]\nhost = \"localhost\"\n"},
+		{"app.cfg", "{\"name\": \"myapp\", \"port\": 8080}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var cfg appConfig
+			if err := conf.Load(path, &cfg); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Name != "myapp" || cfg.Port != 8080 {
+				t.Errorf("got %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"config", "config.conf", "config.yaml"} {
+		var cfg appConfig
+		err := conf.Load(filepath.Join(dir, name), &cfg)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: expected fs.ErrNotExist, got: %v", name, err)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{"a": 1}`, "json"},
+		{"[1, 2]", "json"},
+		{"[server]\nport = 1\n", "toml"},
+		{"[[servers]]\nname = \"a\"\n", "toml"},
+		{"---\na: 1\n", "yaml"},
+		{"- a\n- b\n", "yaml"},
+		{"plain text", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := conf.DetectFormat([]byte(tt.content)); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSaveWithFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.conf")
+
+	original := appConfig{Name: "myapp", Port: 8080}
+	if err := conf.Save(path, &original, conf.WithFormat("yaml")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.LoadFromBytes(data, "yaml", &appConfig{}); err != nil {
+		t.Errorf("saved file is not YAML: %v\n%s", err, data)
+	}
+
+	var loaded appConfig
+	if err := conf.Load(path, &loaded, conf.WithFormat("yaml")); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Name != "myapp" || loaded.Port != 8080 {
+		t.Errorf("got %+v", loaded)
+	}
+}
+
+func TestLoadWithFormatOverridesExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("name = \"myapp\"\nport = 8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg appConfig
+	if err := conf.Load(path, &cfg, conf.WithFormat("toml")); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Name != "myapp" || cfg.Port != 8080 {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestLoadWithFormatErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("name: [unclosed\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := conf.Load(path, &appConfig{}, conf.WithFormat("nope"))
+	if !errors.Is(err, conf.ErrUnsupportedFormat) {
+		t.Errorf("unknown format: got %v, want ErrUnsupportedFormat", err)
+	}
+
+	err = conf.Load(path, &appConfig{}, conf.WithFormat("yaml"))
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("decode error should mention %s, got %v", path, err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	src := []byte(`{"name": "myapp", "port": 8080, "ratio": 1.0, "database": {"user": "admin", "host": "localhost"}}`)
+
+	data, err := conf.Convert(src, "json", "toml")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	want := "name = \"myapp\"\nport = 8080\nratio = 1.0\n\n[database]\n  user = \"admin\"\n  host = \"localhost\"\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+
+	data, err = conf.Convert(data, "toml", "json")
+	if err != nil {
+		t.Fatalf("Convert back failed: %v", err)
+	}
+	want = "{\n  \"name\": \"myapp\",\n  \"port\": 8080,\n  \"ratio\": 1.0,\n  \"database\": {\n    \"user\": \"admin\",\n    \"host\": \"localhost\"\n  }\n}"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestConvertScalars(t *testing.T) {
+	data, err := conf.Convert([]byte("day: 2001-12-14\nat: 2001-12-14T21:59:43Z\n"), "yaml", "toml")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := "day = 2001-12-14\nat = 2001-12-14T21:59:43Z\n"; string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+
+	data, err = conf.Convert([]byte("day: 2001-12-14\n"), "yaml", "yaml")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := "day: 2001-12-14\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+
+	// Local times and local date-times have no YAML timestamp form.
+	data, err = conf.Convert([]byte("lt = 03:04:05\nldt = 2001-12-14T21:59:43\n"), "toml", "yaml")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(string(data), "!!timestamp") {
+		t.Errorf("unexpected tag:\n%s", data)
+	}
+	var back map[string]any
+	if err := conf.LoadFromBytes(data, "yaml", &back); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v\n%s", err, data)
+	}
+	if back["lt"] != "03:04:05" || back["ldt"] != "2001-12-14T21:59:43" {
+		t.Errorf("got %v", back)
+	}
+
+	for _, src := range []string{`{"a": null}`, `{"db": {"host": null}}`, `{"db": {"tls": {"on": null}}, "x": [{"y": 1}]}`} {
+		_, err := conf.Convert([]byte(src), "json", "toml")
+		if err == nil || !strings.Contains(err.Error(), "null") {
+			t.Errorf("%s: expected a null error, got: %v", src, err)
+		}
+	}
+	_, err = conf.Convert([]byte(`{"db": {"host": null}}`), "json", "toml")
+	if err == nil || !strings.Contains(err.Error(), "db.host") {
+		t.Errorf("expected the error to name db.host, got: %v", err)
+	}
+}
+
+func TestConvertUnsupportedFormat(t *testing.T) {
+	_, err := conf.Convert([]byte(`{}`), "json", "nope")
+	if !errors.Is(err, conf.ErrUnsupportedFormat) {
+		t.Errorf("got %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestConvertFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(src, []byte("b = 1\na = \"x\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "out", "config.yaml")
+	if err := conf.ConvertFile(src, dst); err != nil {
+		t.Fatalf("ConvertFile failed: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b: 1\na: x\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestLoadSaveNode(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(src, []byte("zone: eu\nport: 8080\ndatabase:\n    host: localhost\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var n conf.Node
+	if err := conf.Load(src, &n); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	host := n.Get("database").Get("host")
+	if host == nil || host.Line != 4 || host.Column != 11 {
+		t.Errorf("database.host: got %+v, want line 4 column 11", host)
+	}
+
+	dst := filepath.Join(dir, "config.json")
+	if err := conf.Save(dst, &n); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"zone\": \"eu\",\n  \"port\": 8080,\n  \"database\": {\n    \"host\": \"localhost\"\n  }\n}"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestNodeJSONPositions(t *testing.T) {
+	var n conf.Node
+	if err := conf.LoadFromBytes([]byte("{\n  \"a\": 1,\n  \"b\": [true, 2.5]\n}"), "json", &n); err != nil {
+		t.Fatal(err)
+	}
+	item := n.Get("b").Items[1]
+	if item.Kind != conf.FloatNode || item.Line != 3 || item.Column != 15 {
+		t.Errorf("b[1]: got %s at %d:%d, want float at 3:15", item.Kind, item.Line, item.Column)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var doc conf.Node
+	src := "database:\n  hosts:\n    - name: a\n      port: 5432\n    - name: b\n      port: \"5433\"\n  timeout: 30s\nlabels:\n  app.kubernetes.io/name: web\n"
+	if err := conf.LoadFromBytes([]byte(src), "yaml", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if port, err := conf.GetInt(&doc, "database.hosts[0].port"); err != nil || port != 5432 {
+		t.Errorf("GetInt: got %d, %v", port, err)
+	}
+	if port, err := conf.GetInt(&doc, "database.hosts[1].port"); err != nil || port != 5433 {
+		t.Errorf("GetInt from string: got %d, %v", port, err)
+	}
+	if name, err := conf.GetString(&doc, `labels["app.kubernetes.io/name"]`); err != nil || name != "web" {
+		t.Errorf("GetString quoted key: got %q, %v", name, err)
+	}
+	if d, err := conf.GetDuration(&doc, "database.timeout"); err != nil || d != 30*time.Second {
+		t.Errorf("GetDuration: got %v, %v", d, err)
+	}
+
+	errTests := []struct {
+		path string
+		want error
+	}{
+		{"database.missing", conf.ErrKeyNotFound},
+		{"database.hosts[2]", conf.ErrKeyNotFound},
+		{"database.hosts.name", conf.ErrTypeMismatch},
+		{"database[0]", conf.ErrTypeMismatch},
+	}
+	for _, tt := range errTests {
+		if _, err := conf.Lookup(&doc, tt.path); !errors.Is(err, tt.want) {
+			t.Errorf("Lookup(%q): got %v, want %v", tt.path, err, tt.want)
+		}
+	}
+	if _, err := conf.GetInt(&doc, "database.hosts[0].name"); !errors.Is(err, conf.ErrTypeMismatch) {
+		t.Errorf("GetInt on a name: got %v, want ErrTypeMismatch", err)
+	}
+	for _, path := range []string{"database..hosts", "database.hosts[0]port", `labels["a"]b`, "hosts[0"} {
+		if _, err := conf.Lookup(&doc, path); err == nil || errors.Is(err, conf.ErrKeyNotFound) {
+			t.Errorf("Lookup(%q): expected a syntax error, got %v", path, err)
+		}
+	}
+}
+
+func TestAssign(t *testing.T) {
+	var doc conf.Node
+	if err := conf.LoadFromBytes([]byte(`{"name": "app", "hosts": ["a"]}`), "json", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	assignments := []struct {
+		path  string
+		value any
+	}{
+		{"name", "web"},
+		{"hosts[1]", "b"},
+		{"database.port", 5432},
+		{"database.pool.max", 10},
+		{"replicas[0].zone", "eu"},
+		{`labels["a.b/c"]`, true},
+	}
+	for _, a := range assignments {
+		if err := conf.Assign(&doc, a.path, a.value); err != nil {
+			t.Fatalf("Assign(%q) failed: %v", a.path, err)
+		}
+	}
+
+	data, err := conf.SaveToBytes(&doc, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := conf.LoadFromBytes(data, "json", &got); err != nil {
+		t.Fatal(err)
+	}
+	if port, _ := conf.GetInt(&doc, "database.port"); port != 5432 {
+		t.Errorf("database.port: got %d", port)
+	}
+	if zone, _ := conf.GetString(&doc, "replicas[0].zone"); zone != "eu" {
+		t.Errorf("replicas[0].zone: got %q", zone)
+	}
+	if hosts := got["hosts"].([]any); len(hosts) != 2 || hosts[1] != "b" {
+		t.Errorf("hosts: got %v", hosts)
+	}
+
+	if err := conf.Assign(&doc, "hosts[5]", "x"); !errors.Is(err, conf.ErrKeyNotFound) {
+		t.Errorf("out of range: got %v, want ErrKeyNotFound", err)
+	}
+	if err := conf.Assign(&doc, "name.first", "x"); !errors.Is(err, conf.ErrTypeMismatch) {
+		t.Errorf("key on string: got %v, want ErrTypeMismatch", err)
+	}
+	if err := conf.Assign(nil, "a", 1); err == nil {
+		t.Error("expected an error assigning to a nil document")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var a, b conf.Node
+	if err := conf.LoadFromBytes([]byte("port: 8080\nratio: 1\nhosts: [a, b]\ndb:\n  user: admin\n  host: localhost\n"), "yaml", &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.LoadFromBytes([]byte("hosts = [\"a\", \"c\", \"d\"]\nratio = 1.0\nport = 8080\n\n[db]\nhost = \"db.internal\"\n\n[cache]\nttl = 60\n"), "toml", &b); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := conf.Diff(&a, &b)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`~ hosts[1]: "b" -> "c"`,
+		`+ hosts[2]: "d"`,
+		`- db.user: "admin"`,
+		`~ db.host: "localhost" -> "db.internal"`,
+		`+ cache: {"ttl":60}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiffNil(t *testing.T) {
+	var none *conf.Node
+	if changes, err := conf.Diff(none, none); err != nil || len(changes) != 0 {
+		t.Errorf("nil vs nil: got %v, %v", changes, err)
+	}
+	if changes, err := conf.Diff(none, map[string]int{"a": 1}); err != nil || len(changes) != 1 || changes[0].Type != conf.Modified {
+		t.Errorf("nil vs map: got %v, %v", changes, err)
+	}
+
+	a := &conf.Node{Kind: conf.MapNode, Fields: []conf.Field{{Key: "a"}}}
+	changes, err := conf.Diff(a, map[string]int{"a": 1})
+	if err != nil || len(changes) != 1 || changes[0].String() != "~ a: null -> 1" {
+		t.Errorf("nil field: got %v, %v", changes, err)
+	}
+}
+
+func TestDiffFilesAcrossFormats(t *testing.T) {
+	dir := t.TempDir()
+	cfg := appConfig{Name: "myapp", Port: 8080, Debug: true}
+	cfg.Database.Host = "localhost"
+
+	yamlPath := filepath.Join(dir, "config.yaml")
+	tomlPath := filepath.Join(dir, "config.toml")
+	if err := conf.Save(yamlPath, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Save(tomlPath, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := conf.DiffFiles(yamlPath, tomlPath)
+	if err != nil {
+		t.Fatalf("DiffFiles failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	cfg.Port = 9090
+	changes, err = conf.Diff(&appConfig{Name: "myapp", Port: 8080, Debug: true, Database: cfg.Database}, &cfg)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Type != conf.Modified || changes[0].Path != "port" {
+		t.Errorf("got %v", changes)
+	}
+}
+
+func TestApplyMergePatchAcrossFormats(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("name = \"myapp\"\nport = 8080\n\n[database]\nhost = \"localhost\"\nuser = \"admin\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc, patch conf.Node
+	if err := conf.Load(path, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.LoadFromBytes([]byte("port: 9090\ndatabase:\n  user: null\n  pool: {max: 10}\n"), "yaml", &patch); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.ApplyMergePatch(&doc, &patch); err != nil {
+		t.Fatalf("ApplyMergePatch failed: %v", err)
+	}
+	if err := conf.Save(path, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name = \"myapp\"\nport = 9090\n\n[database]\n  host = \"localhost\"\n  [database.pool]\n    max = 10\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestApplyPatchNil(t *testing.T) {
+	patch := map[string]any{"a": 1}
+	if err := conf.ApplyMergePatch(nil, patch); err == nil {
+		t.Error("ApplyMergePatch: expected an error for a nil document")
+	}
+	if err := conf.ApplyJSONPatch(nil, []any{}); err == nil {
+		t.Error("ApplyJSONPatch: expected an error for a nil document")
+	}
+
+	doc := conf.Node{Kind: conf.MapNode, Fields: []conf.Field{{Key: "a", Value: &conf.Node{Kind: conf.IntNode, Value: int64(1)}}}}
+	nilField := &conf.Node{Kind: conf.MapNode, Fields: []conf.Field{{Key: "a"}}}
+	if err := conf.ApplyMergePatch(&doc, nilField); err != nil || len(doc.Fields) != 0 {
+		t.Errorf("nil field: got %v, %v", doc.Fields, err)
+	}
+	if err := conf.ApplyMergePatch(&doc, (*conf.Node)(nil)); err != nil || doc.Kind != conf.NullNode {
+		t.Errorf("nil patch: got %s, %v", doc.Kind, err)
+	}
+	if err := conf.ApplyJSONPatch(&doc, (*conf.Node)(nil)); err == nil {
+		t.Error("ApplyJSONPatch: expected an error for a nil patch")
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	var doc conf.Node
+	if err := conf.LoadFromBytes([]byte(`{"name": "app", "hosts": ["a", "c"], "a/b": {"~x": 1}, "old": true}`), "json", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := []map[string]any{
+		{"op": "add", "path": "/hosts/1", "value": "b"},
+		{"op": "add", "path": "/hosts/-", "value": "d"},
+		{"op": "replace", "path": "/name", "value": "web"},
+		{"op": "test", "path": "/a~1b/~0x", "value": 1.0},
+		{"op": "move", "from": "/old", "path": "/legacy"},
+		{"op": "copy", "from": "/hosts/0", "path": "/primary"},
+		{"op": "remove", "path": "/a~1b"},
+	}
+	if err := conf.ApplyJSONPatch(&doc, patch); err != nil {
+		t.Fatalf("ApplyJSONPatch failed: %v", err)
+	}
+	data, err := cj.New(cj.Compact()).(conf.NodeCodec).EncodeNode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name":"web","hosts":["a","b","c","d"],"legacy":true,"primary":"a"}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	failing := []map[string]any{
+		{"op": "replace", "path": "/name", "value": "changed"},
+		{"op": "test", "path": "/name", "value": "other"},
+	}
+	if err := conf.ApplyJSONPatch(&doc, failing); err == nil {
+		t.Error("expected the test operation to fail")
+	}
+	if name, _ := conf.GetString(&doc, "name"); name != "web" {
+		t.Errorf("failed patch modified the document: name = %q", name)
+	}
+
+	err = conf.ApplyJSONPatch(&doc, []map[string]any{{"op": "remove", "path": "/missing"}})
+	if !errors.Is(err, conf.ErrKeyNotFound) {
+		t.Errorf("got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	var base, layer conf.Node
+	if err := conf.LoadFromBytes([]byte(`{"tags": ["a"], "hosts": ["x"], "servers": [{"name": "web", "port": 80}, {"id": 7, "port": 1}], "debug": true, "labels": {"team": "core", "tier": "1"}}`), "json", &base); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.LoadFromBytes([]byte("tags: [b]\nhosts: [y]\nservers:\n  - {name: web, port: 8080}\n  - {name: api, port: 9090}\n  - {id: 7, port: 2}\ndebug: null\nlabels: {tier: null}\n"), "yaml", &layer); err != nil {
+		t.Fatal(err)
+	}
+
+	err := conf.Merge(&base, &layer,
+		conf.WithStrategy("tags", conf.Append),
+		conf.WithStrategy("hosts", conf.Prepend),
+		conf.WithStrategy("servers", conf.UnionBy()),
+		conf.WithStrategy("labels", conf.DeleteNull),
+	)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	data, err := cj.New(cj.Compact()).(conf.NodeCodec).EncodeNode(&base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"tags":["a","b"],"hosts":["y","x"],"servers":[{"name":"web","port":8080},{"id":7,"port":2},{"name":"api","port":9090}],"debug":null,"labels":{"team":"core"}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	if err := conf.Merge(&base, &layer, conf.WithStrategy("tags", "shuffle")); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	type server struct {
+		Name string `json:"name" yaml:"name" toml:"name"`
+		Port int    `json:"port" yaml:"port" toml:"port"`
+	}
+	type config struct {
+		Name    string            `json:"name" yaml:"name" toml:"name"`
+		Tags    []string          `json:"tags" yaml:"tags" toml:"tags" merge:"append"`
+		Servers []server          `json:"servers" yaml:"servers" toml:"servers" merge:"union=name"`
+		Labels  map[string]string `json:"labels" yaml:"labels" toml:"labels" merge:"delete"`
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	prod := filepath.Join(dir, "prod.toml")
+	if err := os.WriteFile(base, []byte("name: app\ntags: [web]\nservers:\n  - {name: a, port: 1}\n  - {name: b, port: 2}\nlabels: {team: core}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(prod, []byte("tags = [\"prod\"]\n\n[[servers]]\nname = \"b\"\nport = 20\n\n[[servers]]\nname = \"c\"\nport = 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	if err := conf.LoadLayers([]string{base, prod}, &cfg); err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+	if cfg.Name != "app" || strings.Join(cfg.Tags, ",") != "web,prod" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	wantServers := []server{{"a", 1}, {"b", 20}, {"c", 3}}
+	if len(cfg.Servers) != len(wantServers) {
+		t.Fatalf("got servers %+v, want %+v", cfg.Servers, wantServers)
+	}
+	for i := range wantServers {
+		if cfg.Servers[i] != wantServers[i] {
+			t.Errorf("servers[%d]: got %+v, want %+v", i, cfg.Servers[i], wantServers[i])
+		}
+	}
+
+	// Options take precedence over struct tags.
+	cfg = config{}
+	if err := conf.LoadLayers([]string{base, prod}, &cfg, conf.WithStrategy("tags", conf.Replace)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cfg.Tags, ",") != "prod" {
+		t.Errorf("got tags %v, want [prod]", cfg.Tags)
+	}
+
+	if err := conf.LoadLayers([]string{base, filepath.Join(dir, "missing.yaml")}, &cfg); err == nil {
+		t.Error("expected error for missing layer")
+	}
+}
+
+func TestLoadLayersSources(t *testing.T) {
+	type database struct {
+		Host string `json:"host" yaml:"host" toml:"host"`
+		Port int    `json:"port" yaml:"port" toml:"port"`
+	}
+	type config struct {
+		Name     string   `json:"name" yaml:"name" toml:"name"`
+		Port     int      `json:"port" yaml:"port" toml:"port"`
+		Tags     []string `json:"tags" yaml:"tags" toml:"tags" merge:"append"`
+		Database database `json:"database" yaml:"database" toml:"database"`
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	prod := filepath.Join(dir, "prod.toml")
+	if err := os.WriteFile(base, []byte("name: app\ntags: [web]\ndatabase:\n  host: db.internal\n  port: 5432\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(prod, []byte("port = 9090\ntags = [\"prod\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		cfg config
+		md  conf.Metadata
+	)
+	if err := conf.LoadLayers([]string{base, prod}, &cfg, conf.WithMetadata(&md)); err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+	if cfg.Name != "app" || cfg.Port != 9090 || strings.Join(cfg.Tags, ",") != "web,prod" || cfg.Database.Port != 5432 {
+		t.Errorf("got %+v", cfg)
+	}
+
+	sources := map[string]string{
+		"name":          base + ":1:7",
+		"port":          prod + ":1:1",
+		"tags[0]":       base + ":2:8",
+		"tags[1]":       prod, // TOML arrays have no element positions
+		"database.host": base + ":4:9",
+		"database.port": base + ":5:9",
+	}
+	for path, wantSource := range sources {
+		if got := md[path].String(); got != wantSource {
+			t.Errorf("source of %s: got %q, want %q", path, got, wantSource)
+		}
+	}
+	if len(md) != len(sources) {
+		t.Errorf("got %d sources, want %d:\n%s", len(md), len(sources), md)
+	}
+}
+
+func TestLoadSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("name: app\nlabels:\n  app.kubernetes.io/name: web\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		cfg map[string]any
+		md  conf.Metadata
+	)
+	if err := conf.Load(path, &cfg, conf.WithMetadata(&md)); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if src, ok := md.Source(`labels["app.kubernetes.io/name"]`); !ok || src.String() != path+":3:27" {
+		t.Errorf("source of the label: got %v, %v", src, ok)
+	}
+	if src, ok := md.Source("name"); !ok || src.Kind != conf.FileSource || src.Name != path || src.Line != 1 {
+		t.Errorf("source of name: got %+v, %v", src, ok)
+	}
+	for _, path := range []string{"labels", "missing", "name["} {
+		if src, ok := md.Source(path); ok {
+			t.Errorf("Source(%q): got %v, want none", path, src)
+		}
+	}
+
+	if err := conf.LoadFromBytes([]byte(`{"port": 8080}`), "json", &cfg, conf.WithMetadata(&md)); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if src, ok := md.Source("port"); !ok || src.String() != "<input>:1:10" {
+		t.Errorf("source of port: got %v, %v", src, ok)
+	}
+	if len(md) != 1 {
+		t.Errorf("metadata not replaced:\n%s", md)
+	}
+}
+
+// countingCodec counts the documents it decodes.
+type countingCodec struct {
+	conf.Codec
+	decodes int
+}
+
+func (c *countingCodec) Decode(data []byte, v any) error {
+	c.decodes++
+	return c.Codec.Decode(data, v)
+}
+
+func TestLoadSourcesFieldNames(t *testing.T) {
+	type config struct {
+		ListenAddr string `conf:"listen_addr,alias=bind"`
+		MaxConns   int    `conf:"max_conns"`
+	}
+	codec := &countingCodec{Codec: conf.Get("json")}
+	conf.Register("counting-json", codec)
+
+	var (
+		cfg config
+		md  conf.Metadata
+	)
+	err := conf.LoadFromBytes([]byte(`{"bind": "x", "maxConns": 3}`), "counting-json", &cfg,
+		conf.WithMetadata(&md), conf.WithKeyMatch(conf.KeyNormalized))
+	if err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if codec.decodes != 1 {
+		t.Errorf("document decoded %d times, want once", codec.decodes)
+	}
+	if src, ok := md.Source("listen_addr"); !ok || src.Kind != conf.FileSource {
+		t.Errorf("source of listen_addr: got %v, %v", src, ok)
+	}
+	if _, ok := md.Source("max_conns"); !ok {
+		t.Errorf("no source for max_conns:\n%s", md)
+	}
+	for _, path := range []string{"bind", "maxConns"} {
+		if src, ok := md.Source(path); ok {
+			t.Errorf("Source(%q): got %v, want none", path, src)
+		}
+	}
+}
+
+type taggedDatabase struct {
+	Host string `conf:"host,required"`
+	Port int    `conf:"port,omitempty"`
+}
+
+type taggedConfig struct {
+	Name     string            `conf:"name"`
+	MaxConns int               `conf:"max_conns"`
+	Rate     float64           `conf:"rate"`
+	Tags     []string          `conf:"tags,omitempty"`
+	Labels   map[string]string `conf:"labels,omitempty"`
+	Database *taggedDatabase   `conf:"database"`
+	Started  time.Time         `conf:"started"`
+	Legacy   string            `json:"legacy_json" yaml:"legacy_yaml" toml:"legacy_toml"`
+	Secret   string            `conf:"-"`
+}
+
+func TestConfTagAcrossFormats(t *testing.T) {
+	original := taggedConfig{
+		Name:     "app",
+		MaxConns: 10,
+		Rate:     0.5,
+		Tags:     []string{"a", "b"},
+		Database: &taggedDatabase{Host: "db"},
+		Started:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Legacy:   "old",
+		Secret:   "hidden",
+	}
+	for _, format := range []string{"json", "yaml", "toml"} {
+		t.Run(format, func(t *testing.T) {
+			data, err := conf.SaveToBytes(original, format)
+			if err != nil {
+				t.Fatalf("SaveToBytes failed: %v", err)
+			}
+			text := string(data)
+			for _, want := range []string{"max_conns", "legacy_" + format} {
+				if !strings.Contains(text, want) {
+					t.Errorf("output does not contain %q:\n%s", want, text)
+				}
+			}
+			for _, unwanted := range []string{"labels", "port", "hidden", "MaxConns"} {
+				if strings.Contains(text, unwanted) {
+					t.Errorf("output contains %q:\n%s", unwanted, text)
+				}
+			}
+
+			var decoded taggedConfig
+			if err := conf.LoadFromBytes(data, format, &decoded); err != nil {
+				t.Fatalf("LoadFromBytes failed: %v", err)
+			}
+			want := original
+			want.Secret = ""
+			if decoded.Name != want.Name || decoded.MaxConns != want.MaxConns || decoded.Rate != want.Rate ||
+				strings.Join(decoded.Tags, ",") != "a,b" || decoded.Database == nil || *decoded.Database != *want.Database ||
+				!decoded.Started.Equal(want.Started) || decoded.Legacy != want.Legacy || decoded.Secret != "" {
+				t.Errorf("got %+v, want %+v", decoded, want)
+			}
+		})
+	}
+}
+
+func TestConfTagNilFields(t *testing.T) {
+	// Nil slices, maps and pointers are left out, since TOML cannot
+	// write null.
+	for _, format := range []string{"json", "yaml", "toml"} {
+		data, err := conf.SaveToBytes(taggedConfig{Name: "app"}, format)
+		if err != nil {
+			t.Fatalf("%s: SaveToBytes failed: %v", format, err)
+		}
+		for _, unwanted := range []string{"tags", "database", "null"} {
+			if strings.Contains(string(data), unwanted) {
+				t.Errorf("%s: output contains %q:\n%s", format, unwanted, data)
+			}
+		}
+	}
+}
+
+func TestConfTagRequired(t *testing.T) {
+	var cfg taggedConfig
+	err := conf.LoadFromBytes([]byte("name: app\ndatabase:\n  port: 5432\n"), "yaml", &cfg)
+	if !errors.Is(err, conf.ErrMissingField) || !strings.Contains(err.Error(), "database.host") {
+		t.Errorf("expected ErrMissingField for database.host, got %v", err)
+	}
+
+	err = conf.LoadFromBytes([]byte(`{"max_conns": "many"}`), "json", &cfg)
+	if !errors.Is(err, conf.ErrTypeMismatch) || !strings.Contains(err.Error(), "max_conns") {
+		t.Errorf("expected ErrTypeMismatch for max_conns, got %v", err)
+	}
+}
+
+func TestConfTagGoName(t *testing.T) {
+	type config struct {
+		MaxConns int `conf:"max_conns"`
+		Timeout  int
+	}
+	var cfg config
+	if err := conf.LoadFromBytes([]byte(`{"MaxConns": 10, "timeout": 5}`), "json", &cfg); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if cfg.MaxConns != 0 {
+		t.Errorf("MaxConns set through its Go name: %d", cfg.MaxConns)
+	}
+	if cfg.Timeout != 5 {
+		t.Errorf("Timeout: got %d, want 5", cfg.Timeout)
+	}
+}
+
+func TestWithKeyMatch(t *testing.T) {
+	type pool struct {
+		MaxConns int
+		IdleTime time.Duration `yaml:"idle_time"`
+	}
+	type config struct {
+		Name string `conf:"name"`
+		Pool pool
+	}
+	inputs := map[string]string{
+		"json": `{"Name": "app", "pool": {"max_conns": 10, "IDLE-TIME": 5}}`,
+		"yaml": "NAME: app\nPool:\n  maxConns: 10\n  idleTime: 5\n",
+		"toml": "name = \"app\"\n\n[POOL]\nmax-conns = 10\nidle_time = 5\n",
+	}
+	for format, input := range inputs {
+		t.Run(format, func(t *testing.T) {
+			var cfg config
+			if err := conf.LoadFromBytes([]byte(input), format, &cfg, conf.WithKeyMatch(conf.KeyNormalized)); err != nil {
+				t.Fatalf("LoadFromBytes failed: %v", err)
+			}
+			if cfg.Name != "app" || cfg.Pool.MaxConns != 10 || cfg.Pool.IdleTime != 5 {
+				t.Errorf("got %+v", cfg)
+			}
+		})
+	}
+
+	var cfg config
+	if err := conf.LoadFromBytes([]byte("NAME: app\nPOOL:\n  MAXCONNS: 10\n  max_conns: 20\n"), "yaml", &cfg, conf.WithKeyMatch(conf.KeyFold)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "app" || cfg.Pool.MaxConns != 10 {
+		t.Errorf("KeyFold: got %+v, want name app and max conns 10", cfg)
+	}
+
+	cfg = config{}
+	if err := conf.LoadFromBytes([]byte("NAME: app\n"), "yaml", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "" {
+		t.Errorf("keys should match exactly by default, got name %q", cfg.Name)
+	}
+}
+
+type level int
+
+func TestDecodeHooks(t *testing.T) {
+	conf.RegisterDecodeHook(func(n *conf.Node) (level, error) {
+		switch n.Value {
+		case "debug":
+			return 0, nil
+		case "error":
+			return 2, nil
+		}
+		return 0, errors.New("unknown level")
+	})
+
+	type config struct {
+		Timeout  time.Duration   `conf:"timeout"`
+		Retries  []time.Duration `conf:"retries"`
+		Addr     net.IP          `conf:"addr"`
+		Upstream *url.URL        `conf:"upstream"`
+		Match    regexp.Regexp   `conf:"match"`
+		Max      *big.Int        `conf:"max"`
+		MaxBody  conf.ByteSize   `conf:"max_body"`
+		Level    level           `conf:"level"`
+	}
+	inputs := map[string]string{
+		"json": `{"timeout": "30s", "retries": ["1s", 2000000000], "addr": "10.0.0.1", "upstream": "https://example.com/api",
+			"match": "^a+$", "max": "123456789012345678901234567890", "max_body": "512MiB", "level": "error"}`,
+		"yaml": "timeout: 30s\nretries: [1s, 2000000000]\naddr: 10.0.0.1\nupstream: https://example.com/api\n" +
+			"match: ^a+$\nmax: \"123456789012345678901234567890\"\nmax_body: 512MiB\nlevel: error\n",
+		"toml": "timeout = \"30s\"\nretries = [\"1s\", 2000000000]\naddr = \"10.0.0.1\"\nupstream = \"https://example.com/api\"\n" +
+			"match = \"^a+$\"\nmax = \"123456789012345678901234567890\"\nmax_body = \"512MiB\"\nlevel = \"error\"\n",
+	}
+	for format, input := range inputs {
+		t.Run(format, func(t *testing.T) {
+			var cfg config
+			if err := conf.LoadFromBytes([]byte(input), format, &cfg); err != nil {
+				t.Fatalf("LoadFromBytes failed: %v", err)
+			}
+			if cfg.Timeout != 30*time.Second || len(cfg.Retries) != 2 || cfg.Retries[1] != 2*time.Second {
+				t.Errorf("got timeout %v and retries %v", cfg.Timeout, cfg.Retries)
+			}
+			if !cfg.Addr.Equal(net.IPv4(10, 0, 0, 1)) {
+				t.Errorf("got addr %v", cfg.Addr)
+			}
+			if cfg.Upstream == nil || cfg.Upstream.Host != "example.com" {
+				t.Errorf("got upstream %v", cfg.Upstream)
+			}
+			if !cfg.Match.MatchString("aaa") || cfg.Match.MatchString("b") {
+				t.Errorf("got match %v", &cfg.Match)
+			}
+			if cfg.Max == nil || cfg.Max.String() != "123456789012345678901234567890" {
+				t.Errorf("got max %v", cfg.Max)
+			}
+			if cfg.MaxBody != 512<<20 || cfg.Level != 2 {
+				t.Errorf("got max body %d and level %d", cfg.MaxBody, cfg.Level)
+			}
+		})
+	}
+
+	var cfg config
+	err := conf.LoadFromBytes([]byte(`{"timeout": "soon"}`), "json", &cfg)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected error naming the field, got %v", err)
+	}
+	if err := conf.LoadFromBytes([]byte(`{"level": "trace"}`), "json", &cfg); err == nil {
+		t.Error("expected error from user hook")
+	}
+
+	// Only ByteSize accepts units; plain integers stay integers.
+	var limits struct {
+		Timeout time.Duration `conf:"timeout"`
+		Max     int64         `conf:"max"`
+	}
+	if err := conf.LoadFromBytes([]byte(`{"timeout": "1s", "max": "1KiB"}`), "json", &limits); !errors.Is(err, conf.ErrTypeMismatch) {
+		t.Errorf("expected ErrTypeMismatch for a byte size in an int64, got %v", err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"512MiB": 512 << 20,
+		"1.5GB":  1_500_000_000,
+		"64k":    64 << 10,
+		"10 kb":  10_000,
+		"2gib":   2 << 30,
+		"100B":   100,
+	} {
+		if got, err := conf.ParseByteSize(s); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "MiB", "12 parsecs", "1e30EiB"} {
+		if _, err := conf.ParseByteSize(s); err == nil {
+			t.Errorf("ParseByteSize(%q): expected error", s)
+		}
+	}
+}
+
+func TestUnitTypes(t *testing.T) {
+	type limits struct {
+		Timeout conf.Duration `conf:"timeout"`
+		MaxBody conf.ByteSize `conf:"max_body"`
+		CPU     conf.Percent  `json:"cpu" yaml:"cpu" toml:"cpu"`
+	}
+	want := limits{
+		Timeout: conf.Duration(90 * time.Minute),
+		MaxBody: 2 * conf.GiB,
+		CPU:     0.75,
+	}
+	for _, format := range []string{"json", "yaml", "toml"} {
+		t.Run(format, func(t *testing.T) {
+			data, err := conf.SaveToBytes(want, format)
+			if err != nil {
+				t.Fatalf("SaveToBytes failed: %v", err)
+			}
+			for _, s := range []string{"1h30m", "2GiB", "75%"} {
+				if !strings.Contains(string(data), s) {
+					t.Errorf("output does not contain %q:\n%s", s, data)
+				}
+			}
+			var got limits
+			if err := conf.LoadFromBytes(data, format, &got); err != nil {
+				t.Fatalf("LoadFromBytes failed: %v", err)
+			}
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	// Integers are read by conf itself, so the struct uses conf tags.
+	var got struct {
+		Timeout conf.Duration `conf:"timeout"`
+		MaxBody conf.ByteSize `conf:"max_body"`
+		CPU     conf.Percent  `conf:"cpu"`
+	}
+	if err := conf.LoadFromBytes([]byte(`{"timeout": 1000000000, "max_body": 1024, "cpu": "12.5%"}`), "json", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Timeout != conf.Duration(time.Second) || got.MaxBody != conf.KiB || got.CPU != 0.125 {
+		t.Errorf("got %+v", got)
+	}
+	if err := conf.LoadFromBytes([]byte(`{"cpu": 75}`), "json", &got); err == nil {
+		t.Error("expected error for a percentage without %")
+	}
+
+	for v, want := range map[fmt.Stringer]string{
+		conf.Duration(time.Hour):               "1h",
+		conf.Duration(90 * time.Second):        "1m30s",
+		conf.Duration(1500 * time.Millisecond): "1.5s",
+		conf.Duration(0):                       "0s",
+		conf.ByteSize(1536 * 1024):             "1536KiB",
+		conf.ByteSize(100):                     "100B",
+		conf.ByteSize(0):                       "0B",
+		conf.Percent(0.07):                     "7%",
+	} {
+		if got := v.String(); got != want {
+			t.Errorf("%T(%v).String() = %q, want %q", v, v, got, want)
+		}
+	}
+}
+
+func TestAliasesAndDeprecations(t *testing.T) {
+	type server struct {
+		ListenAddr string `conf:"listen_addr,alias=bind,alias=addr,deprecated=renamed in v2, use listen_addr"`
+		Workers    int    `conf:"workers,deprecated"`
+		Timeout    int    `conf:"timeout,alias=timeout_secs"`
+	}
+	type config struct {
+		Server server `conf:"server"`
+	}
+
+	var got []conf.Deprecation
+	handler := conf.WithDeprecationHandler(func(d conf.Deprecation) {
+		got = append(got, d)
+	})
+	var cfg config
+	input := "server:\n  bind: \":8080\"\n  workers: 4\n  timeout_secs: 30\n"
+	if err := conf.LoadFromBytes([]byte(input), "yaml", &cfg, handler); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if cfg.Server.ListenAddr != ":8080" || cfg.Server.Workers != 4 || cfg.Server.Timeout != 30 {
+		t.Errorf("got %+v", cfg.Server)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got deprecations %v, want bind and workers", got)
+	}
+	want := "server.bind (line 2) is deprecated, use server.listen_addr: renamed in v2, use listen_addr"
+	if s := got[0].String(); s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	if got[1].Path != "server.workers" || got[1].Replacement != "" || got[1].Message != "" {
+		t.Errorf("got %+v for workers", got[1])
+	}
+
+	for _, input := range []string{
+		`{"server": {"listen_addr": ":9090", "addr": ":7070"}}`,
+		`{"server": {"addr": ":7070", "listen_addr": ":9090"}}`,
+	} {
+		got = nil
+		cfg = config{}
+		if err := conf.LoadFromBytes([]byte(input), "json", &cfg, handler); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Server.ListenAddr != ":9090" || len(got) != 1 || got[0].Path != "server.addr" {
+			t.Errorf("%s: the new name should win and the alias be reported, got %q and %v", input, cfg.Server.ListenAddr, got)
+		}
+	}
+
+	data, err := conf.SaveToBytes(cfg, "toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); !strings.Contains(s, "listen_addr") || strings.Contains(s, "bind") || strings.Contains(s, " addr =") {
+		t.Errorf("Save should write only the new name:\n%s", data)
+	}
+
+	if err := conf.LoadFromBytes([]byte(`{"server": {"bind": ":1"}}`), "json", &cfg, conf.WithDeprecationHandler(nil)); err != nil {
+		t.Fatal(err)
+	}
+}
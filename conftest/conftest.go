@@ -1,9 +1,10 @@
-// Package conftest provides a conformance test suite for conf.Codec
-// implementations. Adapter packages should use Suite in their tests
-// to verify correct behavior.
+// Package conftest provides conformance test suites for conf.Codec and
+// conf.Compressor implementations. Adapter packages should use Suite or
+// CompressorSuite in their tests to verify correct behavior.
 package conftest
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 
@@ -133,3 +134,61 @@ func Suite(t *testing.T, codec conf.Codec) {
 		}
 	})
 }
+
+// CompressorSuite runs a conformance test suite against a compressor
+// implementation.
+func CompressorSuite(t *testing.T, compressor conf.Compressor) {
+	t.Helper()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		original := bytes.Repeat([]byte("name = \"myapp\"\nport = 8080\n"), 64)
+
+		compressed, err := compressor.Compress(original)
+		if err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+		if bytes.Equal(compressed, original) {
+			t.Error("Compress returned its input unchanged")
+		}
+
+		decompressed, err := compressor.Decompress(compressed)
+		if err != nil {
+			t.Fatalf("Decompress failed: %v", err)
+		}
+		if !bytes.Equal(decompressed, original) {
+			t.Errorf("round-trip mismatch: got %d bytes, want %d", len(decompressed), len(original))
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		compressed, err := compressor.Compress(nil)
+		if err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+		decompressed, err := compressor.Decompress(compressed)
+		if err != nil {
+			t.Fatalf("Decompress failed: %v", err)
+		}
+		if len(decompressed) != 0 {
+			t.Errorf("expected empty output, got %q", decompressed)
+		}
+	})
+
+	t.Run("Extensions", func(t *testing.T) {
+		exts := compressor.Extensions()
+		if len(exts) == 0 {
+			t.Error("Extensions() returned empty slice")
+		}
+		for _, ext := range exts {
+			if ext == "" || ext[0] != '.' {
+				t.Errorf("extension should start with dot, got %q", ext)
+			}
+		}
+	})
+
+	t.Run("DecompressInvalidData", func(t *testing.T) {
+		if _, err := compressor.Decompress([]byte("<<<invalid>>>")); err == nil {
+			t.Error("expected error when decompressing invalid data, got nil")
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package conf
+
+import "fmt"
+
+// Convert translates data from one registered format to another, such as
+// "json" to "toml". The document passes through a Node tree, so key order
+// and the distinction between integers, floats and datetimes survive as
+// far as both formats allow. Values the target format cannot represent,
+// such as nulls in TOML, are reported by its codec.
+func Convert(data []byte, from, to string) ([]byte, error) {
+	src := Get(from)
+	if src == nil {
+		return nil, fmt.Errorf("%w: %q (available: %v)", ErrUnsupportedFormat, from, Available())
+	}
+	dst := Get(to)
+	if dst == nil {
+		return nil, fmt.Errorf("%w: %q (available: %v)", ErrUnsupportedFormat, to, Available())
+	}
+
+	n, err := decodeNode(src, data)
+	if err != nil {
+		return nil, fmt.Errorf("conf: decoding %s: %w", from, err)
+	}
+	data, err = encodeNode(dst, n)
+	if err != nil {
+		return nil, fmt.Errorf("conf: encoding %s: %w", to, err)
+	}
+	return data, nil
+}
+
+// ConvertFile reads the file at src and writes it to dst in the format
+// given by the extension of dst. The format of src is detected as in Load.
+// Parent directories of dst are created automatically if they do not
+// exist.
+func ConvertFile(src, dst string) error {
+	from, data, err := readFile(src, newOptions(nil))
+	if err != nil {
+		return err
+	}
+	to, err := codecForPath(dst)
+	if err != nil {
+		return err
+	}
+
+	n, err := decodeNode(from, data)
+	if err != nil {
+		return fmt.Errorf("conf: decoding %s: %w", src, err)
+	}
+	data, err = encodeNode(to, n)
+	if err != nil {
+		return fmt.Errorf("conf: encoding %s: %w", dst, err)
+	}
+	return writeFile(dst, data)
+}
@@ -0,0 +1,184 @@
+// Package cue provides a CUE codec for the conf package.
+// Import this package to register the "cue" codec:
+//
+//	import _ "github.com/nuln/conf/cue"
+//
+// Decode compiles the document and unifies it with the constraints it
+// declares, together with any schemas supplied through Schema, so type and
+// value constraints (ranges, enums, regular expressions) are enforced at
+// decode time:
+//
+//	port:  int & >=1024 & <=65535
+//	level: "debug" | "info" | "warn"
+//	port:  8080
+//	level: "info"
+//
+// The unified value must be concrete; it is then decoded into the target
+// using its json tags. Encode writes the value as CUE data.
+package cue
+
+import (
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/format"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.Register("cue", New())
+}
+
+// Option configures a CUE codec created by New.
+type Option func(*cueCodec)
+
+// Schema adds CUE source whose constraints every decoded document must
+// satisfy. It may be given multiple times; all schemas are unified.
+func Schema(src string) Option {
+	return func(c *cueCodec) {
+		c.schemas = append(c.schemas, src)
+	}
+}
+
+// New returns a new CUE codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &cueCodec{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type cueCodec struct {
+	schemas []string
+}
+
+func (c *cueCodec) Encode(v any) ([]byte, error) {
+	ctx := cuecontext.New()
+	val := ctx.Encode(v)
+	if err := val.Err(); err != nil {
+		return nil, err
+	}
+
+	node := val.Syntax(cue.Final(), cue.Concrete(true))
+	// Write struct fields at the top level of the file rather than
+	// wrapping them in braces.
+	if lit, ok := node.(*ast.StructLit); ok {
+		node = &ast.File{Decls: lit.Elts}
+	}
+	return format.Node(node)
+}
+
+func (c *cueCodec) Decode(data []byte, v any) error {
+	val, err := c.compile(data)
+	if err != nil {
+		return err
+	}
+	return val.Decode(v)
+}
+
+// DecodeNode decodes data into a tree that keeps the order in which fields
+// are declared, along with their positions.
+func (c *cueCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	val, err := c.compile(data)
+	if err != nil {
+		return nil, err
+	}
+	return node(val)
+}
+
+func (c *cueCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	return c.Encode(n)
+}
+
+// compile compiles data, unifies it with the schemas and checks that the
+// result is concrete.
+func (c *cueCodec) compile(data []byte) (cue.Value, error) {
+	// Contexts are not safe for concurrent use, so each call gets its own.
+	ctx := cuecontext.New()
+	val := ctx.CompileBytes(data, cue.Filename("<input>"))
+	for i, src := range c.schemas {
+		schema := ctx.CompileString(src, cue.Filename(fmt.Sprintf("<schema %d>", i)))
+		if err := schema.Err(); err != nil {
+			return cue.Value{}, err
+		}
+		val = val.Unify(schema)
+	}
+
+	if err := val.Validate(cue.Concrete(true)); err != nil {
+		return cue.Value{}, err
+	}
+	return val, nil
+}
+
+func node(v cue.Value) (*conf.Node, error) {
+	n := &conf.Node{}
+	if pos := v.Pos(); pos.IsValid() {
+		n.Line, n.Column = pos.Line(), pos.Column()
+	}
+
+	var err error
+	switch v.Kind() {
+	case cue.StructKind:
+		n.Kind, n.Fields = conf.MapNode, []conf.Field{}
+		iter, err := v.Fields()
+		if err != nil {
+			return nil, err
+		}
+		for iter.Next() {
+			value, err := node(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			n.Fields = append(n.Fields, conf.Field{Key: iter.Selector().Unquoted(), Value: value})
+		}
+	case cue.ListKind:
+		n.Kind, n.Items = conf.ListNode, []*conf.Node{}
+		iter, err := v.List()
+		if err != nil {
+			return nil, err
+		}
+		for iter.Next() {
+			item, err := node(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			n.Items = append(n.Items, item)
+		}
+	case cue.IntKind:
+		n.Kind = conf.IntNode
+		if n.Value, err = v.Int64(); err != nil {
+			n.Kind = conf.FloatNode
+			n.Value, err = v.Float64()
+		}
+	case cue.FloatKind, cue.NumberKind:
+		n.Kind = conf.FloatNode
+		n.Value, err = v.Float64()
+	case cue.StringKind:
+		n.Kind = conf.StringNode
+		n.Value, err = v.String()
+	case cue.BytesKind:
+		n.Kind = conf.BytesNode
+		n.Value, err = v.Bytes()
+	case cue.BoolKind:
+		n.Kind = conf.BoolNode
+		n.Value, err = v.Bool()
+	case cue.NullKind:
+		n.Kind = conf.NullNode
+	default:
+		return nil, fmt.Errorf("cue: unsupported value of kind %s", v.Kind())
+	}
+	return n, err
+}
+
+func (c *cueCodec) Extensions() []string {
+	return []string{".cue"}
+}
+
+var (
+	_ conf.Codec     = (*cueCodec)(nil)
+	_ conf.NodeCodec = (*cueCodec)(nil)
+)
@@ -0,0 +1,92 @@
+package cue_test
+
+import (
+	"testing"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cc "github.com/nuln/conf/cue"
+)
+
+func TestCUE(t *testing.T) {
+	conftest.Suite(t, cc.New())
+}
+
+func TestCUERegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "cue" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("cue should be registered via init()")
+	}
+}
+
+type serverConfig struct {
+	Port  int    `json:"port"`
+	Level string `json:"level"`
+}
+
+func TestCUEInlineConstraints(t *testing.T) {
+	input := []byte(`
+port:  int & >=1024 & <=65535
+level: "debug" | "info" | "warn"
+
+port:  8080
+level: "info"
+`)
+
+	var cfg serverConfig
+	if err := cc.New().Decode(input, &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Port != 8080 || cfg.Level != "info" {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+
+	bad := []byte("port: int & >=1024\nport: 80\nlevel: \"info\"\n")
+	if err := cc.New().Decode(bad, &cfg); err == nil {
+		t.Error("expected constraint violation for port 80")
+	}
+}
+
+func TestCUESchemaOption(t *testing.T) {
+	codec := cc.New(cc.Schema(`level: =~"^(debug|info|warn)$"`))
+
+	var cfg serverConfig
+	if err := codec.Decode([]byte("port: 80\nlevel: \"warn\"\n"), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if err := codec.Decode([]byte("port: 80\nlevel: \"trace\"\n"), &cfg); err == nil {
+		t.Error("expected schema violation for level trace")
+	}
+	if err := codec.Decode([]byte("port: 80\n"), &cfg); err == nil {
+		t.Error("expected error for non-concrete level")
+	}
+}
+
+func TestCUENode(t *testing.T) {
+	nc := cc.New().(conf.NodeCodec)
+	n, err := nc.DecodeNode([]byte("zone: \"eu\"\nport: 8080\nratio: 0.5\n"))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	if n.Fields[0].Key != "zone" || n.Get("port").Kind != conf.IntNode || n.Get("ratio").Kind != conf.FloatNode {
+		t.Errorf("unexpected tree: %+v", n.Fields)
+	}
+	if got := n.Get("ratio").Line; got != 3 {
+		t.Errorf("ratio: got line %d, want 3", got)
+	}
+
+	data, err := nc.EncodeNode(n)
+	if err != nil {
+		t.Fatalf("EncodeNode failed: %v", err)
+	}
+	if want := "zone:  \"eu\"\nport:  8080\nratio: 0.5\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,81 @@
+package conf
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Deprecation describes a deprecated key found while decoding a struct.
+//
+// Keys are deprecated by the conf tag of the field they set. An alias
+// option gives an old name that still sets the field, and a deprecated
+// option, optionally followed by a message, marks the aliases as
+// deprecated, or the field itself when it has none:
+//
+//	type Server struct {
+//	    ListenAddr string `conf:"listen_addr,alias=bind,deprecated=use listen_addr"`
+//	    Workers    int    `conf:"workers,deprecated"`
+//	}
+//
+// The message runs to the end of the tag, so it may contain commas. A
+// field may have several aliases; if a document gives both a field and
+// one of its aliases, the field's own name wins, whatever their order.
+// Save and SaveToBytes write only the field's own name.
+type Deprecation struct {
+	// Path is the path of the deprecated key, such as "server.bind".
+	Path string
+
+	// Replacement is the path of the key to use instead, such as
+	// "server.listen_addr", or "" if the field itself is deprecated.
+	Replacement string
+
+	// Message is the message of the deprecated option, if any.
+	Message string
+
+	// Line is the line of the key's value, or 0 if unknown.
+	Line int
+}
+
+// String describes d, as in
+// "server.bind (line 3) is deprecated, use server.listen_addr".
+func (d Deprecation) String() string {
+	s := d.Path
+	if d.Line > 0 {
+		s += fmt.Sprintf(" (line %d)", d.Line)
+	}
+	s += " is deprecated"
+	if d.Replacement != "" {
+		s += ", use " + d.Replacement
+	}
+	if d.Message != "" {
+		s += ": " + d.Message
+	}
+	return s
+}
+
+// WithDeprecationHandler makes Load, LoadFromBytes, LoadAll, LoadLayers and
+// Node.Decode call fn for each deprecated key they decode, instead of
+// logging a warning with slog. A nil fn discards them.
+func WithDeprecationHandler(fn func(Deprecation)) Option {
+	return func(o *options) {
+		if fn == nil {
+			fn = func(Deprecation) {}
+		}
+		o.deprecation = fn
+	}
+}
+
+// logDeprecation is the default deprecation handler.
+func logDeprecation(d Deprecation) {
+	attrs := []any{"key", d.Path}
+	if d.Replacement != "" {
+		attrs = append(attrs, "use", d.Replacement)
+	}
+	if d.Line > 0 {
+		attrs = append(attrs, "line", d.Line)
+	}
+	if d.Message != "" {
+		attrs = append(attrs, "message", d.Message)
+	}
+	slog.Warn("conf: deprecated key", attrs...)
+}
@@ -0,0 +1,175 @@
+package conf
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChangeType is the kind of a Change.
+type ChangeType uint8
+
+// Change types.
+const (
+	Added ChangeType = iota + 1
+	Removed
+	Modified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return fmt.Sprintf("ChangeType(%d)", uint8(t))
+}
+
+// Change is a difference between two documents at a single path.
+type Change struct {
+	Type ChangeType
+
+	// Path is the location of the change in the syntax of Lookup.
+	Path string
+
+	// Old and New are the values before and after the change. Old is nil
+	// for Added and New is nil for Removed.
+	Old, New *Node
+}
+
+// String formats c as a single line such as
+// "~ database.port: 5432 -> 5433".
+func (c Change) String() string {
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New.text())
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old.text())
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Old.text(), c.New.text())
+}
+
+// text formats n as compact JSON for messages.
+func (n *Node) text() string {
+	data, err := n.MarshalJSON()
+	if err != nil {
+		return fmt.Sprint(n.Value)
+	}
+	return string(data)
+}
+
+// Diff compares two configurations and returns the paths that were added,
+// removed or modified going from a to b. a and b may be *Node trees or any
+// values accepted by NewNode, such as structs or maps.
+//
+// The comparison is structural, so documents in different formats compare
+// equal when they hold the same data: key order is ignored, integers equal
+// floats of the same value, and datetimes equal strings holding them.
+// Lists are compared element by element. Changes are reported in the
+// order of a, followed by keys that only exist in b. A nil *Node compares
+// as null.
+func Diff(a, b any) ([]Change, error) {
+	na, err := NewNode(a)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := NewNode(b)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	diff(nil, na, nb, &changes)
+	return changes, nil
+}
+
+// DiffFiles loads the files at a and b, whose formats are detected as in
+// Load and may differ, and compares them with Diff.
+func DiffFiles(a, b string) ([]Change, error) {
+	var na, nb Node
+	if err := Load(a, &na); err != nil {
+		return nil, err
+	}
+	if err := Load(b, &nb); err != nil {
+		return nil, err
+	}
+	return Diff(&na, &nb)
+}
+
+func diff(path []pathElem, a, b *Node, changes *[]Change) {
+	if a == nil {
+		a = &Node{Kind: NullNode}
+	}
+	if b == nil {
+		b = &Node{Kind: NullNode}
+	}
+	switch {
+	case a.Kind == MapNode && b.Kind == MapNode:
+		for _, f := range a.Fields {
+			at := append(path[:len(path):len(path)], pathElem{key: f.Key, index: -1})
+			if nb, ok := mapField(b, f.Key); ok {
+				diff(at, f.Value, nb, changes)
+			} else {
+				*changes = append(*changes, Change{Type: Removed, Path: formatPath(at), Old: f.Value})
+			}
+		}
+		for _, f := range b.Fields {
+			if _, ok := mapField(a, f.Key); !ok {
+				at := append(path[:len(path):len(path)], pathElem{key: f.Key, index: -1})
+				*changes = append(*changes, Change{Type: Added, Path: formatPath(at), New: f.Value})
+			}
+		}
+	case a.Kind == ListNode && b.Kind == ListNode:
+		for i := range max(len(a.Items), len(b.Items)) {
+			at := append(path[:len(path):len(path)], pathElem{index: i})
+			switch {
+			case i >= len(b.Items):
+				*changes = append(*changes, Change{Type: Removed, Path: formatPath(at), Old: a.Items[i]})
+			case i >= len(a.Items):
+				*changes = append(*changes, Change{Type: Added, Path: formatPath(at), New: b.Items[i]})
+			default:
+				diff(at, a.Items[i], b.Items[i], changes)
+			}
+		}
+	default:
+		if !scalarEqual(a, b) {
+			*changes = append(*changes, Change{Type: Modified, Path: formatPath(path), Old: a, New: b})
+		}
+	}
+}
+
+// mapField returns the value of the field key of the map n, which may be
+// nil, and whether the field exists.
+func mapField(n *Node, key string) (*Node, bool) {
+	for i := len(n.Fields) - 1; i >= 0; i-- {
+		if n.Fields[i].Key == key {
+			return n.Fields[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// scalarEqual reports whether a and b hold the same value. Maps and lists
+// are never equal to scalars.
+func scalarEqual(a, b *Node) bool {
+	if a.Kind > b.Kind {
+		a, b = b, a
+	}
+	switch {
+	case a.Kind == MapNode || a.Kind == ListNode || b.Kind == MapNode || b.Kind == ListNode:
+		return false
+	case a.Kind == IntNode && b.Kind == FloatNode:
+		return float64(a.Value.(int64)) == b.Value.(float64)
+	case a.Kind == StringNode && b.Kind == TimeNode:
+		t, err := time.Parse(time.RFC3339Nano, a.Value.(string))
+		return err == nil && t.Equal(b.Value.(time.Time))
+	case a.Kind != b.Kind:
+		return false
+	case a.Kind == TimeNode:
+		return a.Value.(time.Time).Equal(b.Value.(time.Time))
+	case a.Kind == BytesNode:
+		return string(a.Value.([]byte)) == string(b.Value.([]byte))
+	}
+	return a.Value == b.Value
+}
@@ -6,10 +6,28 @@
 //
 // # Supported Codecs
 //
+//   - cbor — fxamacker/cbor       (import _ "github.com/nuln/conf/cbor")
+//   - cue — cuelang.org/go         (import _ "github.com/nuln/conf/cue")
 //   - json — Go stdlib encoding/json (import _ "github.com/nuln/conf/json")
+//   - jsonc — JSONC / JSON5 dialects (import _ "github.com/nuln/conf/jsonc")
+//   - jsonl — JSON Lines / NDJSON (import _ "github.com/nuln/conf/jsonl")
+//   - jsonnet — google/go-jsonnet (import _ "github.com/nuln/conf/jsonnet")
+//   - msgpack — vmihailenco/msgpack (import _ "github.com/nuln/conf/msgpack")
+//   - plist — howett.net/plist     (import _ "github.com/nuln/conf/plist")
 //   - toml — BurntSushi/toml      (import _ "github.com/nuln/conf/toml")
+//   - xml — Go stdlib encoding/xml (import _ "github.com/nuln/conf/xml")
 //   - yaml — gopkg.in/yaml.v3     (import _ "github.com/nuln/conf/yaml")
 //
+// # Compressed Files
+//
+// A compressor extension after the codec extension, as in "config.json.gz",
+// makes Load decompress and Save compress the file transparently. Built-in
+// compressors register themselves like codecs:
+//
+//   - gzip  — .gz  (import _ "github.com/nuln/conf/compress/gzip")
+//   - zstd  — .zst (import _ "github.com/nuln/conf/compress/zstd")
+//   - bzip2 — .bz2 (import _ "github.com/nuln/conf/compress/bzip2")
+//
 // # Quick Start
 //
 //	import (
@@ -1,13 +1,29 @@
 // Package drivers is a convenience package that registers all built-in
-// codecs. Import it with a blank identifier to make all codecs available:
+// codecs and compressors. Import it with a blank identifier to make all
+// codecs available:
 //
 //	import _ "github.com/nuln/conf/drivers"
+//
+// This links in every codec, including cue and jsonnet, which embed their
+// language runtimes and add substantially to binary size. Programs that
+// need only a few formats should import those codec packages instead.
 package drivers
 
 import (
 	"github.com/nuln/conf"
+	_ "github.com/nuln/conf/cbor"
+	_ "github.com/nuln/conf/compress/bzip2"
+	_ "github.com/nuln/conf/compress/gzip"
+	_ "github.com/nuln/conf/compress/zstd"
+	_ "github.com/nuln/conf/cue"
 	_ "github.com/nuln/conf/json"
+	_ "github.com/nuln/conf/jsonc"
+	_ "github.com/nuln/conf/jsonl"
+	_ "github.com/nuln/conf/jsonnet"
+	_ "github.com/nuln/conf/msgpack"
+	_ "github.com/nuln/conf/plist"
 	_ "github.com/nuln/conf/toml"
+	_ "github.com/nuln/conf/xml"
 	_ "github.com/nuln/conf/yaml"
 )
 
@@ -24,8 +40,8 @@ func List() []string {
 // and decodes its contents into v. v must be a pointer.
 //
 // This is a convenience wrapper for conf.Load.
-func Load(path string, v any) error {
-	return conf.Load(path, v)
+func Load(path string, v any, opts ...conf.Option) error {
+	return conf.Load(path, v, opts...)
 }
 
 // Save encodes v and writes the result to the file at path.
@@ -33,6 +49,6 @@ func Load(path string, v any) error {
 // Parent directories are created automatically if they do not exist.
 //
 // This is a convenience wrapper for conf.Save.
-func Save(path string, v any) error {
-	return conf.Save(path, v)
+func Save(path string, v any, opts ...conf.Option) error {
+	return conf.Save(path, v, opts...)
 }
@@ -0,0 +1,173 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Editor is an optional interface implemented by codecs that can modify
+// individual keys of an existing document while preserving its comments,
+// key order and formatting.
+type Editor interface {
+	// Edit parses data into an editable document.
+	Edit(data []byte) (EditableDocument, error)
+}
+
+// EditableDocument is a parsed document returned by an Editor.
+// Paths are lists of map keys from the document root.
+type EditableDocument interface {
+	// Get decodes the value at path into v.
+	// It returns an error wrapping ErrKeyNotFound if path does not exist.
+	Get(path []string, v any) error
+
+	// Set replaces the value at path, creating missing keys as needed.
+	Set(path []string, value any) error
+
+	// Delete removes the key at path.
+	// It returns an error wrapping ErrKeyNotFound if path does not exist.
+	Delete(path []string) error
+
+	// Bytes returns the current contents of the document.
+	Bytes() []byte
+}
+
+// Document is a configuration document being modified by Edit or EditBytes.
+// Keys are addressed by paths such as "database.port", as in Lookup; keys
+// containing dots are quoted, as in `labels["app.kubernetes.io/name"]`.
+// List indices are not supported.
+type Document struct {
+	doc EditableDocument
+}
+
+// Get decodes the value at path into v. An empty path decodes the whole
+// document.
+func (d *Document) Get(path string, v any) error {
+	keys, err := editKeys(path)
+	if err != nil {
+		return err
+	}
+	return d.doc.Get(keys, v)
+}
+
+// Set replaces the value at path, creating missing keys as needed.
+func (d *Document) Set(path string, value any) error {
+	keys, err := editKeys(path)
+	if err != nil {
+		return err
+	}
+	return d.doc.Set(keys, value)
+}
+
+// Delete removes the key at path.
+func (d *Document) Delete(path string) error {
+	keys, err := editKeys(path)
+	if err != nil {
+		return err
+	}
+	return d.doc.Delete(keys)
+}
+
+// Edit applies fn to the document stored at path and writes the result
+// back, preserving everything fn does not change. The format is detected
+// from the file extension unless forced with WithFormat, and its codec must
+// implement Editor.
+// The file is left untouched if fn returns an error.
+func Edit(path string, fn func(doc *Document) error, opts ...Option) error {
+	codec, data, err := readFile(path, newOptions(opts))
+	if err != nil {
+		return err
+	}
+
+	data, err = edit(codec, data, fn)
+	if err != nil {
+		return fmt.Errorf("conf: editing %s: %w", path, err)
+	}
+
+	if err := replaceFile(path, data); err != nil {
+		return fmt.Errorf("conf: writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// replaceFile atomically replaces the contents of the file at path with
+// data, by writing a temporary file in the same directory and renaming it
+// over the original, whose permissions it keeps. A symbolic link at path
+// is followed rather than replaced.
+func replaceFile(path string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+// EditBytes applies fn to data in the named format and returns the
+// modified document.
+func EditBytes(data []byte, format string, fn func(doc *Document) error) ([]byte, error) {
+	codec := Get(format)
+	if codec == nil {
+		return nil, fmt.Errorf("%w: %q (available: %v)", ErrUnsupportedFormat, format, Available())
+	}
+	data, err := edit(codec, data, fn)
+	if err != nil {
+		return nil, fmt.Errorf("conf: editing %s: %w", format, err)
+	}
+	return data, nil
+}
+
+func edit(codec Codec, data []byte, fn func(doc *Document) error) ([]byte, error) {
+	editor, ok := codec.(Editor)
+	if !ok {
+		return nil, fmt.Errorf("%w: codec does not support editing", ErrUnsupportedFormat)
+	}
+	doc, err := editor.Edit(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(&Document{doc: doc}); err != nil {
+		return nil, err
+	}
+	return doc.Bytes(), nil
+}
+
+// editKeys parses path into the map keys expected by an EditableDocument.
+func editKeys(path string) ([]string, error) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(elems))
+	for i, e := range elems {
+		if e.index >= 0 {
+			return nil, fmt.Errorf("conf: invalid path %q: list indices cannot be edited", path)
+		}
+		keys[i] = e.key
+	}
+	return keys, nil
+}
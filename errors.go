@@ -7,4 +7,16 @@ var (
 	// ErrUnsupportedFormat is returned when no codec is registered for the
 	// requested format or file extension.
 	ErrUnsupportedFormat = errors.New("conf: unsupported format")
+
+	// ErrKeyNotFound is returned when a key path does not exist in a
+	// document.
+	ErrKeyNotFound = errors.New("conf: key not found")
+
+	// ErrTypeMismatch is returned when a value in a document does not have
+	// the type an operation requires.
+	ErrTypeMismatch = errors.New("conf: type mismatch")
+
+	// ErrMissingField is returned when a struct field tagged required has
+	// no value in a document.
+	ErrMissingField = errors.New("conf: missing required field")
 )
@@ -25,6 +25,18 @@ func Register(name string, codec Codec) {
 	}
 }
 
+// RegisterNamed registers a codec under the given name without indexing
+// its extensions, so it is only selected explicitly by name (e.g. through
+// LoadFromBytes or SaveToBytes). Use it for configured variants of a
+// built-in codec that should not take over file extension detection:
+//
+//	conf.RegisterNamed("json-tabs", json.New(json.Indent("\t")))
+func RegisterNamed(name string, codec Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[name] = codec
+}
+
 // Get returns the codec registered under the given name.
 // Returns nil if not found.
 func Get(name string) Codec {
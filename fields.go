@@ -0,0 +1,22 @@
+package conf
+
+// tagFor returns the struct tag key that codec reads field names from.
+func tagFor(codec Codec) string {
+	if exts := codec.Extensions(); len(exts) > 0 {
+		switch exts[0] {
+		case ".yaml", ".yml":
+			return "yaml"
+		case ".toml":
+			return "toml"
+		case ".xml":
+			return "xml"
+		case ".plist":
+			return "plist"
+		case ".cbor":
+			return "cbor"
+		case ".msgpack":
+			return "msgpack"
+		}
+	}
+	return "json"
+}
@@ -0,0 +1,292 @@
+// Package flags defines command-line flags for the fields of a
+// configuration struct, so that binaries need not duplicate each field as
+// a flag by hand:
+//
+//	var cfg Config
+//	fs := flag.NewFlagSet("myapp", flag.ExitOnError)
+//	b, err := flags.Bind(fs, &cfg)
+//	fs.Parse(os.Args[1:])
+//	err = conf.LoadLayers(paths, &cfg)
+//	err = b.Apply()
+//
+// Flags are named after the key path of their field, using the same json,
+// yaml and toml tag names as the codecs: the field at database.host is set
+// by --database.host. Only flags given on the command line are applied, so
+// the loaded values are kept for the others. A usage struct tag gives the
+// help text of a flag.
+package flags
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/fields"
+)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// Binding holds the flags defined for the fields of a struct by Bind.
+type Binding struct {
+	v      reflect.Value
+	values []*value
+}
+
+// Bind defines a flag on fs for each field of the struct v points to that
+// holds a scalar, a list of scalars (given as comma-separated values) or a
+// type implementing encoding.TextUnmarshaler. The current values of the
+// fields are shown as defaults. Fields whose flag name is already defined
+// on fs are left to that flag.
+func Bind(fs *flag.FlagSet, v any) (*Binding, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("flags: want a pointer to a struct, got %T", v)
+	}
+	b := &Binding{v: rv.Elem()}
+	fields.Walk(rv.Type(), "", false, func(f fields.Field) {
+		if f.List || !fields.IsLeaf(f.Type) {
+			return
+		}
+		name := strings.Join(f.Keys, ".")
+		if fs.Lookup(name) != nil {
+			return
+		}
+		val := &value{name: name, index: f.Index, typ: f.Type}
+		if current, ok := lookup(b.v, f.Index); ok {
+			val.def = format(current)
+		}
+		fs.Var(val, name, f.Tag.Get("usage"))
+		b.values = append(b.values, val)
+	})
+	return b, nil
+}
+
+// Parse binds the fields of v to fs, parses args and applies the flags
+// given in them. Use Bind instead when flags must be parsed before v is
+// loaded, for example to read the path of the configuration file.
+func Parse(fs *flag.FlagSet, v any, args []string) error {
+	b, err := Bind(fs, v)
+	if err != nil {
+		return err
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return b.Apply()
+}
+
+// Apply sets the fields of the bound struct whose flags were given on the
+// command line, allocating nil pointers to nested structs as needed.
+func (b *Binding) Apply() error {
+	for _, val := range b.values {
+		if !val.set {
+			continue
+		}
+		f, err := field(b.v, val.index)
+		if err != nil {
+			return fmt.Errorf("flags: --%s: %w", val.name, err)
+		}
+		f.Set(val.parsed)
+	}
+	return nil
+}
+
+// Record records the flags given on the command line as the sources of
+// their values in md, as filled by conf.WithMetadata, replacing the
+// sources of any list elements they override.
+func (b *Binding) Record(md conf.Metadata) {
+	for _, val := range b.values {
+		if !val.set {
+			continue
+		}
+		for path := range md {
+			if strings.HasPrefix(path, val.name+"[") {
+				delete(md, path)
+			}
+		}
+		md[val.name] = conf.Source{Kind: conf.FlagSource, Name: val.name}
+	}
+}
+
+// value is the flag.Value of a field. It keeps the parsed value until
+// Apply, so that flags can be parsed before the struct is loaded.
+type value struct {
+	name   string
+	index  []int
+	typ    reflect.Type
+	def    string
+	parsed reflect.Value
+	set    bool
+}
+
+func (v *value) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.def
+}
+
+func (v *value) Set(s string) error {
+	parsed, err := parse(v.typ, s)
+	if err != nil {
+		return err
+	}
+	v.parsed, v.set = parsed, true
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set by --name alone.
+func (v *value) IsBoolFlag() bool {
+	t := v.typ
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool && !fields.IsText(t)
+}
+
+// lookup returns the field at index within v, reporting false if a nil
+// pointer is in the way.
+func lookup(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// field returns the settable field at index within v, allocating nil
+// pointers along the way.
+func field(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					if !v.CanSet() {
+						return reflect.Value{}, fmt.Errorf("cannot allocate unexported %s", v.Type())
+					}
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	if !v.CanSet() {
+		return reflect.Value{}, errors.New("field cannot be set")
+	}
+	return v, nil
+}
+
+// format formats v as a flag default, or returns "" for zero values.
+func format(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() {
+		return ""
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = format(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// parse parses s into a value of type t.
+func parse(t reflect.Type, s string) (reflect.Value, error) {
+	if t.Kind() == reflect.Pointer {
+		elem, err := parse(t.Elem(), s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(elem)
+		return p, nil
+	}
+
+	out := reflect.New(t).Elem()
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetInt(int64(d))
+		return out, nil
+	case fields.IsText(t):
+		err := out.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		return out, err
+	}
+
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		out.SetString(s)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		out.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(s, 0, t.Bits())
+		out.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		u, err = strconv.ParseUint(s, 0, t.Bits())
+		out.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, t.Bits())
+		out.SetFloat(f)
+	case reflect.Slice, reflect.Array:
+		var parts []string
+		if strings.TrimSpace(s) != "" {
+			parts = strings.Split(s, ",")
+		}
+		if t.Kind() == reflect.Array && len(parts) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("want %d values, got %d", t.Len(), len(parts))
+		}
+		if t.Kind() == reflect.Slice {
+			out.Set(reflect.MakeSlice(t, len(parts), len(parts)))
+		}
+		for i, part := range parts {
+			elem, err := parse(t.Elem(), strings.TrimSpace(part))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(elem)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type %s", t)
+	}
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("want %s", t)
+	}
+	return out, nil
+}
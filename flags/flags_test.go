@@ -0,0 +1,113 @@
+package flags_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/flags"
+	_ "github.com/nuln/conf/json"
+)
+
+type database struct {
+	Host string `json:"host" yaml:"host" toml:"host" usage:"database host"`
+	Port int    `json:"port" yaml:"port" toml:"port"`
+}
+
+type config struct {
+	Name     string        `json:"name" yaml:"name" toml:"name"`
+	Debug    bool          `json:"debug" yaml:"debug" toml:"debug"`
+	Timeout  time.Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+	Tags     []string      `json:"tags" yaml:"tags" toml:"tags"`
+	Database database      `json:"database" yaml:"database" toml:"database"`
+	Cache    *database     `json:"cache" yaml:"cache" toml:"cache"`
+	Ignored  string        `json:"-"`
+}
+
+func TestParseOverlaysSetFlags(t *testing.T) {
+	cfg := config{
+		Name:     "app",
+		Timeout:  time.Second,
+		Tags:     []string{"web"},
+		Database: database{Host: "localhost", Port: 5432},
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	args := []string{"--debug", "--database.port=6432", "--tags", "a, b", "--timeout", "1m30s", "--cache.host", "redis"}
+	if err := flags.Parse(fs, &cfg, args); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if cfg.Name != "app" || cfg.Database.Host != "localhost" {
+		t.Errorf("flags that were not given changed the config: %+v", cfg)
+	}
+	if !cfg.Debug || cfg.Database.Port != 6432 || cfg.Timeout != 90*time.Second {
+		t.Errorf("flags were not applied: %+v", cfg)
+	}
+	if strings.Join(cfg.Tags, "|") != "a|b" {
+		t.Errorf("got tags %q, want [a b]", cfg.Tags)
+	}
+	if cfg.Cache == nil || cfg.Cache.Host != "redis" {
+		t.Errorf("got cache %+v, want host redis", cfg.Cache)
+	}
+	if fs.Lookup("ignored") != nil || fs.Lookup("Ignored") != nil {
+		t.Error("field tagged json:\"-\" should have no flag")
+	}
+}
+
+func TestBindBeforeLoad(t *testing.T) {
+	var cfg config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	b, err := flags.Bind(fs, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"--name", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conf.LoadFromBytes([]byte(`{"name": "file", "database": {"host": "db"}}`), "json", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Apply(); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if cfg.Name != "cli" || cfg.Database.Host != "db" {
+		t.Errorf("got %+v, want name from flag and host from file", cfg)
+	}
+
+	md := conf.Metadata{"name": {Kind: conf.FileSource, Name: "config.json"}}
+	b.Record(md)
+	if got := md["name"].String(); got != "flag --name" {
+		t.Errorf("source of name: got %q, want %q", got, "flag --name")
+	}
+}
+
+func TestUsageAndErrors(t *testing.T) {
+	cfg := config{Database: database{Port: 5432}}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	if _, err := flags.Bind(fs, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	fs.PrintDefaults()
+	for _, want := range []string{"-database.host", "database host", "(default 5432)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("usage does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	fs.SetOutput(io.Discard)
+	err := fs.Parse([]string{"--database.port", "http"})
+	if err == nil || !strings.Contains(err.Error(), "want int") {
+		t.Errorf("expected invalid value error, got %v", err)
+	}
+
+	if _, err := flags.Bind(flag.NewFlagSet("test", flag.ContinueOnError), cfg); err == nil {
+		t.Error("expected error for non-pointer")
+	}
+}
@@ -3,6 +3,29 @@ module github.com/nuln/conf
 go 1.24
 
 require (
+	cuelang.org/go v0.14.1
 	github.com/BurntSushi/toml v1.6.0
+	github.com/dsnet/compress v0.0.1
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/google/go-jsonnet v0.21.0
+	github.com/klauspost/compress v1.18.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	gopkg.in/yaml.v3 v3.0.1
+	howett.net/plist v1.0.1
+)
+
+require (
+	github.com/cockroachdb/apd/v3 v3.2.1 // indirect
+	github.com/emicklei/proto v1.14.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	github.com/protocolbuffers/txtpbfmt v0.0.0-20250627152318-f293424e46b5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/crypto v0.40.0 // indirect
+	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.27.0 // indirect
+	sigs.k8s.io/yaml v1.4.0 // indirect
 )
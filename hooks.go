@@ -0,0 +1,174 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	hooksMu sync.RWMutex
+	hooks   = make(map[reflect.Type]func(n *Node) (reflect.Value, error))
+)
+
+// RegisterDecodeHook registers fn to convert document values into values
+// of type T. It applies wherever T, or a pointer to T, appears in a value
+// decoded by conf itself, the same way in every format: structs using conf
+// tags, any struct loaded with WithKeyMatch, and the targets of
+// Node.Decode. Other values are decoded by their codec, which reads T by
+// its own rules. fn is not called for null values.
+// Registering a hook for a type replaces the previous one, including the
+// built-in hooks:
+//
+//   - time.Duration from strings such as "1h30m", or integer nanoseconds
+//     given as integers or text
+//   - net.IP from strings
+//   - url.URL from strings
+//   - regexp.Regexp from strings holding a pattern
+//   - big.Int from integers or strings holding one
+//   - Duration, ByteSize and Percent, as described for each
+//
+// Hooks are typically registered in init functions:
+//
+//	conf.RegisterDecodeHook(func(n *conf.Node) (Level, error) {
+//	    s, ok := n.Value.(string)
+//	    if !ok {
+//	        return 0, fmt.Errorf("want a level name, got %s", n.Kind)
+//	    }
+//	    return ParseLevel(s)
+//	})
+func RegisterDecodeHook[T any](fn func(n *Node) (T, error)) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks[reflect.TypeFor[T]()] = func(n *Node) (reflect.Value, error) {
+		v, err := fn(n)
+		return reflect.ValueOf(&v).Elem(), err
+	}
+}
+
+// hookFor returns the decode hook registered for t, or nil.
+func hookFor(t reflect.Type) func(n *Node) (reflect.Value, error) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return hooks[t]
+}
+
+func init() {
+	RegisterDecodeHook(func(n *Node) (time.Duration, error) {
+		switch n.Kind {
+		case StringNode:
+			// Formats without integer types, such as XML, write
+			// durations as integer nanoseconds in text.
+			s := n.Value.(string)
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return time.Duration(i), nil
+			}
+			return time.ParseDuration(s)
+		case IntNode:
+			return time.Duration(n.Value.(int64)), nil
+		}
+		return 0, fmt.Errorf("want a duration, got %s", n.Kind)
+	})
+	RegisterDecodeHook(func(n *Node) (net.IP, error) {
+		s, err := hookString(n, "an IP address")
+		if err != nil {
+			return nil, err
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", s)
+		}
+		return ip, nil
+	})
+	RegisterDecodeHook(func(n *Node) (url.URL, error) {
+		s, err := hookString(n, "a URL")
+		if err != nil {
+			return url.URL{}, err
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	})
+	RegisterDecodeHook(func(n *Node) (regexp.Regexp, error) {
+		s, err := hookString(n, "a regular expression")
+		if err != nil {
+			return regexp.Regexp{}, err
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return regexp.Regexp{}, err
+		}
+		return *re, nil
+	})
+	RegisterDecodeHook(func(n *Node) (big.Int, error) {
+		var i big.Int
+		switch n.Kind {
+		case IntNode:
+			i.SetInt64(n.Value.(int64))
+			return i, nil
+		case StringNode:
+			if _, ok := i.SetString(n.Value.(string), 0); ok {
+				return i, nil
+			}
+			return i, fmt.Errorf("invalid integer %q", n.Value)
+		case FloatNode:
+			if f := n.Value.(float64); f == math.Trunc(f) && !math.IsInf(f, 0) {
+				big.NewFloat(f).Int(&i)
+				return i, nil
+			}
+		}
+		return i, fmt.Errorf("want an integer, got %s", n.Kind)
+	})
+	RegisterDecodeHook(func(n *Node) (Duration, error) {
+		if n.Kind == IntNode {
+			return Duration(n.Value.(int64)), nil
+		}
+		var d Duration
+		s, err := hookString(n, "a duration")
+		if err == nil {
+			err = d.UnmarshalText([]byte(s))
+		}
+		return d, err
+	})
+	RegisterDecodeHook(func(n *Node) (ByteSize, error) {
+		if x, ok := intValue(n); ok {
+			return ByteSize(x), nil
+		}
+		var b ByteSize
+		s, err := hookString(n, "a byte size")
+		if err == nil {
+			err = b.UnmarshalText([]byte(s))
+		}
+		return b, err
+	})
+	RegisterDecodeHook(func(n *Node) (Percent, error) {
+		var p Percent
+		s, err := hookString(n, "a percentage")
+		if err == nil {
+			err = p.UnmarshalText([]byte(s))
+		}
+		return p, err
+	})
+}
+
+// hookString returns the string held by n, which a hook expects to be what.
+// Bytes are accepted as text, since msgpack writes text marshalers as
+// binary.
+func hookString(n *Node, what string) (string, error) {
+	switch n.Kind {
+	case StringNode:
+		return n.Value.(string), nil
+	case BytesNode:
+		return string(n.Value.([]byte)), nil
+	}
+	return "", fmt.Errorf("want %s, got %s", what, n.Kind)
+}
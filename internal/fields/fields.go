@@ -0,0 +1,299 @@
+// Package fields walks the fields of configuration structs and names them
+// by their conf tags or the tags of the built-in codecs.
+package fields
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// Field is a field found by Walk or Of.
+type Field struct {
+	reflect.StructField
+
+	// Keys is the key path of the field in a document.
+	Keys []string
+
+	// Index is the sequence of field indexes leading to the field from
+	// the walked struct, through nested structs and pointers to them. It
+	// is not meaningful when List is set.
+	Index []int
+
+	// List reports whether the path to the field passes through a list.
+	List bool
+
+	// Implicit reports whether the field is named after its Go name, having
+	// no tag naming it.
+	Implicit bool
+
+	opts string
+}
+
+// Walk calls fn for each field of the struct type t, recursing into struct
+// fields and the elements of lists and pointers. Fields are named as by Of.
+// With all set, fn is called once for each distinct name instead of only
+// the first, since documents may use any of them.
+func Walk(t reflect.Type, tag string, all bool, fn func(Field)) {
+	if t == nil {
+		return
+	}
+	seen := map[reflect.Type]bool{}
+	var walk func(t reflect.Type, keys []string, index []int, list bool)
+	walk = func(t reflect.Type, keys []string, index []int, list bool) {
+		for {
+			if t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			} else if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 {
+				t, list = t.Elem(), true
+			} else {
+				break
+			}
+		}
+		if t.Kind() != reflect.Struct || IsText(t) || seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		for _, f := range Of(t, tag, all) {
+			f.Keys = append(keys[:len(keys):len(keys)], f.Keys...)
+			f.Index = append(index[:len(index):len(index)], f.Index...)
+			f.List = list
+			fn(f)
+			walk(f.Type, f.Keys, f.Index, list)
+		}
+	}
+	walk(t, nil, nil, false)
+}
+
+// Of returns the fields of the struct type t, each with a single key. The
+// fields of embedded structs without a name of their own take their place.
+//
+// Fields are named by their conf tag, then by the tag key tag, then by
+// their json, yaml or toml tags, or else by their lower-cased Go name,
+// which all built-in codecs match. Fields named "-" by the first of these
+// tags they have are skipped. With all set, a field is returned once for
+// each of its distinct names.
+func Of(t reflect.Type, tag string, all bool) []Field {
+	return of(t, keys(tag), all)
+}
+
+// Own returns the fields of the struct type t like Of, but ignores conf
+// tags. It is for codecs, which name fields by the tag key tag and the
+// json, yaml and toml tags.
+func Own(t reflect.Type, tag string) []Field {
+	return of(t, keys(tag)[1:], false)
+}
+
+// of implements Of, naming fields by the tag keys tags.
+func of(t reflect.Type, tags []string, all bool) []Field {
+	var out []Field
+	seen := map[reflect.Type]bool{}
+	var collect func(t reflect.Type, index []int)
+	collect = func(t reflect.Type, index []int) {
+		seen[t] = true
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			at := append(index[:len(index):len(index)], i)
+			if ignored(sf, tags) {
+				continue
+			}
+			names := tagNames(sf, tags)
+			if sf.Anonymous && len(names) == 0 {
+				et := sf.Type
+				if et.Kind() == reflect.Pointer {
+					et = et.Elem()
+				}
+				if et.Kind() == reflect.Struct && !IsText(et) && !seen[et] {
+					collect(et, at)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			implicit := len(names) == 0
+			if implicit {
+				names = []string{strings.ToLower(sf.Name), sf.Name}
+			}
+			if !all {
+				names = names[:1]
+			}
+			for _, name := range names {
+				out = append(out, Field{StructField: sf, Keys: []string{name}, Index: at, Implicit: implicit, opts: options(sf, tags)})
+			}
+		}
+	}
+	collect(t, nil)
+	return out
+}
+
+// keys returns the tag keys that name fields, in order of precedence.
+func keys(tag string) []string {
+	return []string{"conf", tag, "json", "yaml", "toml"}
+}
+
+// ignored reports whether sf is excluded by a "-" name in the first of
+// tags that it has.
+func ignored(sf reflect.StructField, tags []string) bool {
+	for _, key := range tags {
+		if v, ok := sf.Tag.Lookup(key); ok && key != "" {
+			name, _, _ := strings.Cut(v, ",")
+			if name != "" || key != "conf" {
+				return name == "-"
+			}
+		}
+	}
+	return false
+}
+
+// options returns the options of sf's conf tag if it has one, and
+// otherwise those of the tag that names it.
+func options(sf reflect.StructField, tags []string) string {
+	for _, key := range tags {
+		if v, ok := sf.Tag.Lookup(key); ok && key != "" {
+			name, opts, _ := strings.Cut(v, ",")
+			if name != "" || key == "conf" {
+				return opts
+			}
+		}
+	}
+	return ""
+}
+
+// Has reports whether the tag naming f, or its conf tag, lists the option
+// opt after the name, as in `conf:"port,omitempty"`.
+func (f Field) Has(opt string) bool {
+	for _, o := range f.options() {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the values of the options of f named key, as in
+// `conf:"listen_addr,alias=bind,alias=addr"`.
+func (f Field) Values(key string) []string {
+	var values []string
+	for _, o := range f.options() {
+		if k, v, ok := strings.Cut(o, "="); ok && k == key {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// options splits the options of f. The value of a deprecated option runs
+// to the end of the tag, so that its message may contain commas.
+func (f Field) options() []string {
+	if f.opts == "" {
+		return nil
+	}
+	opts := strings.Split(f.opts, ",")
+	for i, o := range opts {
+		if strings.HasPrefix(o, "deprecated=") {
+			return append(opts[:i:i], strings.Join(opts[i:], ","))
+		}
+	}
+	return opts
+}
+
+// Names returns the distinct names given to sf by its conf tag, its tag
+// tag and its json, yaml and toml tags, in that order.
+func Names(sf reflect.StructField, tag string) []string {
+	return tagNames(sf, keys(tag))
+}
+
+// tagNames returns the distinct names given to sf by tags, in order.
+func tagNames(sf reflect.StructField, tags []string) []string {
+	var names []string
+	for _, key := range tags {
+		if key == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get(key), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		dup := false
+		for _, n := range names {
+			dup = dup || n == name
+		}
+		if !dup {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var tagged sync.Map // typeKey -> bool
+
+type typeKey struct {
+	t   reflect.Type
+	key string
+}
+
+// Tagged reports whether t, or a type it refers to, has a field with a
+// struct tag named key.
+func Tagged(t reflect.Type, key string) bool {
+	if v, ok := tagged.Load(typeKey{t, key}); ok {
+		return v.(bool)
+	}
+	found := false
+	seen := map[reflect.Type]bool{}
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		if found || seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			visit(t.Elem())
+		case reflect.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case reflect.Struct:
+			for i := range t.NumField() {
+				sf := t.Field(i)
+				if _, ok := sf.Tag.Lookup(key); ok {
+					found = true
+					return
+				}
+				visit(sf.Type)
+			}
+		}
+	}
+	visit(t)
+	tagged.Store(typeKey{t, key}, found)
+	return found
+}
+
+// IsText reports whether values of t decode from text on their own.
+func IsText(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// IsLeaf reports whether a field of type t holds a value that can be given
+// as text: a scalar, a list of scalars or a type that decodes from text.
+func IsLeaf(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if IsText(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Slice && IsLeaf(t.Elem())
+	case reflect.Struct, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan,
+		reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
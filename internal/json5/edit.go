@@ -0,0 +1,526 @@
+package json5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nuln/conf"
+)
+
+// Document is an editable JSON or JSONC document. Modifications are
+// spliced into the original text, so comments and formatting outside the
+// changed values are preserved.
+type Document struct {
+	src  []byte
+	root *node
+}
+
+// node is a parsed value and its byte span in the source.
+type node struct {
+	kind       byte // '{', '[' or 0 for scalars
+	start, end int
+	members    []member
+	items      []*node
+}
+
+// member is an object member. comma is the offset of the comma following
+// the value, or -1.
+type member struct {
+	key   string
+	start int
+	value *node
+	comma int
+}
+
+// Edit parses data into an editable document.
+func Edit(data []byte) (*Document, error) {
+	d := &Document{}
+	if err := d.reset(data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Bytes returns the current contents of the document.
+func (d *Document) Bytes() []byte {
+	return d.src
+}
+
+// Get decodes the value at path into v.
+func (d *Document) Get(path []string, v any) error {
+	n, err := d.lookup(path)
+	if err != nil {
+		return err
+	}
+	return Decode(d.src[n.start:n.end], v)
+}
+
+// Set replaces the value at path, creating missing members as needed.
+func (d *Document) Set(path []string, value any) error {
+	if len(path) == 0 {
+		return fmt.Errorf("json: cannot replace the document root")
+	}
+	if d.root == nil {
+		text, err := render(nest(path, value), "", "  ")
+		if err != nil {
+			return err
+		}
+		// Keep any leading comments of an otherwise empty document.
+		src := append([]byte{}, bytes.TrimRight(d.src, " \t\r\n")...)
+		if len(src) > 0 {
+			src = append(src, '\n')
+		}
+		src = append(src, text...)
+		return d.reset(append(src, '\n'))
+	}
+
+	cur := d.root
+	for i, key := range path {
+		if cur.kind != '{' {
+			return fmt.Errorf("json: cannot set %q: %q is not an object", strings.Join(path, "."),
+				strings.Join(path[:i], "."))
+		}
+		m, ok := cur.member(key)
+		if !ok {
+			return d.insert(cur, path[i:], value)
+		}
+		cur = m.value
+	}
+
+	text, err := render(value, indentAt(d.src, cur.start), d.unit())
+	if err != nil {
+		return err
+	}
+	return d.splice(cur.start, cur.end, text)
+}
+
+// Delete removes the member at path.
+func (d *Document) Delete(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("json: cannot delete the document root")
+	}
+	parent, err := d.lookup(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	if parent.kind != '{' {
+		return fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+	}
+	idx := -1
+	for i, m := range parent.members {
+		if m.key == path[len(path)-1] {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+	}
+
+	m := parent.members[idx]
+	last := idx == len(parent.members)-1
+	end := m.value.end
+	if m.comma >= 0 {
+		end = m.comma + 1
+	}
+
+	if !d.singleLine(parent) && onOwnLine(d.src, m.start) {
+		start := commentsAbove(d.src, lineStart(d.src, m.start))
+		end = restOfLine(d.src, end)
+		if end < len(d.src) {
+			end++
+		}
+		if last && m.comma < 0 && idx > 0 {
+			// Drop the separator before the removed member so that the
+			// document stays valid strict JSON.
+			comma := parent.members[idx-1].comma
+			src := make([]byte, 0, len(d.src))
+			src = append(src, d.src[:comma]...)
+			src = append(src, d.src[comma+1:start]...)
+			src = append(src, d.src[end:]...)
+			return d.reset(src)
+		}
+		return d.splice(start, end, "")
+	}
+
+	start := m.start
+	switch {
+	case last && idx > 0:
+		start = parent.members[idx-1].comma
+		end = m.value.end
+	default:
+		for end < len(d.src) && (d.src[end] == ' ' || d.src[end] == '\t') {
+			end++
+		}
+	}
+	return d.splice(start, end, "")
+}
+
+// insert adds the member keys[0] to obj, nesting the remaining keys.
+func (d *Document) insert(obj *node, keys []string, value any) error {
+	unit := d.unit()
+	indent := indentAt(d.src, obj.start) + unit
+	if len(obj.members) > 0 && onOwnLine(d.src, obj.members[0].start) {
+		indent = indentAt(d.src, obj.members[0].start)
+	}
+
+	key, err := json.Marshal(keys[0])
+	if err != nil {
+		return err
+	}
+
+	if d.singleLine(obj) {
+		text, err := json.Marshal(nest(keys[1:], value))
+		if err != nil {
+			return err
+		}
+		entry := string(key) + ": " + string(text)
+		if len(obj.members) == 0 {
+			return d.splice(obj.start+1, obj.end-1, entry)
+		}
+		last := obj.members[len(obj.members)-1]
+		if last.comma >= 0 {
+			return d.splice(last.comma+1, last.comma+1, " "+entry)
+		}
+		return d.splice(last.value.end, last.value.end, ", "+entry)
+	}
+
+	text, err := render(nest(keys[1:], value), indent, unit)
+	if err != nil {
+		return err
+	}
+	entry := "\n" + indent + string(key) + ": " + text
+
+	if len(obj.members) == 0 {
+		return d.splice(obj.start+1, lineStart(d.src, obj.end-1), entry+"\n")
+	}
+
+	last := obj.members[len(obj.members)-1]
+	pos := last.value.end
+	src := d.src
+	if last.comma >= 0 {
+		pos = last.comma + 1
+		entry += ","
+	} else {
+		src = insertAt(src, pos, ",")
+		pos++
+	}
+	pos = restOfLine(src, pos)
+	return d.reset(insertAt(src, pos, entry))
+}
+
+// lookup returns the node at path.
+func (d *Document) lookup(path []string) (*node, error) {
+	if d.root == nil {
+		return nil, fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+	}
+	cur := d.root
+	for _, key := range path {
+		m, ok := cur.member(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+		}
+		cur = m.value
+	}
+	return cur, nil
+}
+
+// unit returns the indentation step used by the document.
+func (d *Document) unit() string {
+	var find func(n *node) string
+	find = func(n *node) string {
+		for _, m := range n.members {
+			if onOwnLine(d.src, m.start) {
+				outer, inner := indentAt(d.src, n.start), indentAt(d.src, m.start)
+				if strings.HasPrefix(inner, outer) && len(inner) > len(outer) {
+					return inner[len(outer):]
+				}
+			}
+			if m.value.kind == '{' {
+				if u := find(m.value); u != "" {
+					return u
+				}
+			}
+		}
+		return ""
+	}
+	if d.root != nil {
+		if u := find(d.root); u != "" {
+			return u
+		}
+	}
+	return "  "
+}
+
+func (d *Document) singleLine(n *node) bool {
+	return !bytes.Contains(d.src[n.start:n.end], []byte{'\n'})
+}
+
+func (d *Document) splice(start, end int, text string) error {
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+	return d.reset(src)
+}
+
+// reset replaces the document source and parses it again.
+func (d *Document) reset(src []byte) error {
+	p := &parser{scanner: scanner{data: src}}
+	if err := p.skipSpace(); err != nil {
+		return err
+	}
+	var root *node
+	if p.pos < len(src) {
+		var err error
+		if root, err = p.value(); err != nil {
+			return err
+		}
+		if err := p.skipSpace(); err != nil {
+			return err
+		}
+		if p.pos != len(src) {
+			return p.errorf("unexpected data after top-level value")
+		}
+	}
+	d.src, d.root = src, root
+	return nil
+}
+
+func (n *node) member(key string) (member, bool) {
+	if n.kind != '{' {
+		return member{}, false
+	}
+	found, ok := member{}, false
+	for _, m := range n.members {
+		if m.key == key {
+			// Later duplicates win, as in encoding/json.
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+// parser builds a node tree with source positions.
+type parser struct {
+	scanner
+}
+
+func (p *parser) skipSpace() error {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '/':
+			if err := p.skipComment(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) value() (*node, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch c := p.data[p.pos]; c {
+	case '{':
+		return p.object()
+	case '[':
+		return p.array()
+	case '"', '\'':
+		start := p.pos
+		if _, err := p.readString(c); err != nil {
+			return nil, err
+		}
+		return &node{start: start, end: p.pos}, nil
+	default:
+		start := p.pos
+		for p.pos < len(p.data) && !bytes.ContainsAny(p.data[p.pos:p.pos+1], " \t\r\n,:]}/") {
+			p.pos++
+		}
+		if p.pos == start {
+			return nil, p.errorf("unexpected %q", c)
+		}
+		return &node{start: start, end: p.pos}, nil
+	}
+}
+
+func (p *parser) object() (*node, error) {
+	n := &node{kind: '{', start: p.pos}
+	p.pos++
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.data[p.pos] == '}' {
+			p.pos++
+			n.end = p.pos
+			return n, nil
+		}
+
+		m := member{start: p.pos, comma: -1}
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		m.key = key
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.pos++
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if m.value, err = p.value(); err != nil {
+			return nil, err
+		}
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			m.comma = p.pos
+			p.pos++
+		} else if p.pos < len(p.data) && p.data[p.pos] != '}' {
+			return nil, p.errorf("expected ',' or '}' after object member")
+		}
+		n.members = append(n.members, m)
+	}
+}
+
+func (p *parser) array() (*node, error) {
+	n := &node{kind: '[', start: p.pos}
+	p.pos++
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			n.end = p.pos
+			return n, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+		} else if p.pos < len(p.data) && p.data[p.pos] != ']' {
+			return nil, p.errorf("expected ',' or ']' after array element")
+		}
+	}
+}
+
+func (p *parser) key() (string, error) {
+	c := p.data[p.pos]
+	if c == '"' || c == '\'' {
+		raw, err := p.readString(c)
+		if err != nil {
+			return "", err
+		}
+		var key string
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return "", err
+		}
+		return key, nil
+	}
+	if !isIdentStart(c) {
+		return "", p.errorf("unexpected %q, expected object key", c)
+	}
+	return string(p.readIdent()), nil
+}
+
+// nest wraps value in one single-key object per key.
+func nest(keys []string, value any) any {
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = map[string]any{keys[i]: value}
+	}
+	return value
+}
+
+func render(value any, prefix, indent string) (string, error) {
+	data, err := json.MarshalIndent(value, prefix, indent)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func insertAt(src []byte, pos int, text string) []byte {
+	out := make([]byte, 0, len(src)+len(text))
+	out = append(out, src[:pos]...)
+	out = append(out, text...)
+	return append(out, src[pos:]...)
+}
+
+func lineStart(src []byte, pos int) int {
+	return bytes.LastIndexByte(src[:pos], '\n') + 1
+}
+
+// indentAt returns the leading whitespace of the line containing pos.
+func indentAt(src []byte, pos int) string {
+	start := lineStart(src, pos)
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+// commentsAbove returns the start of the comment lines directly above the
+// line starting at pos.
+func commentsAbove(src []byte, pos int) int {
+	for pos > 0 {
+		prev := lineStart(src, pos-1)
+		line := bytes.TrimSpace(src[prev:pos])
+		isComment := bytes.HasPrefix(line, []byte("//")) ||
+			bytes.HasPrefix(line, []byte("/*")) && bytes.HasSuffix(line, []byte("*/"))
+		if !isComment {
+			break
+		}
+		pos = prev
+	}
+	return pos
+}
+
+// onOwnLine reports whether only whitespace precedes pos on its line.
+func onOwnLine(src []byte, pos int) bool {
+	return len(bytes.TrimSpace(src[lineStart(src, pos):pos])) == 0
+}
+
+// restOfLine returns the end of the line containing pos if the remainder
+// of the line holds only whitespace and a line comment, and pos otherwise.
+func restOfLine(src []byte, pos int) int {
+	end := bytes.IndexByte(src[pos:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += pos
+	}
+	rest := bytes.TrimSpace(src[pos:end])
+	if len(rest) > 0 && !bytes.HasPrefix(rest, []byte("//")) {
+		return pos
+	}
+	if end > pos && src[end-1] == '\r' {
+		end--
+	}
+	return end
+}
@@ -0,0 +1,508 @@
+// Package json5 translates human-edited JSON dialects into standard JSON.
+//
+// It accepts JSONC and JSON5: line and block comments, trailing commas,
+// unquoted object keys, single-quoted and multi-line strings, the JSON5
+// string escapes, hexadecimal numbers, numbers with a leading or trailing
+// decimal point or a plus sign, Infinity and NaN. Positions in decoded
+// trees and in errors refer to the original text.
+package json5
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/textpos"
+)
+
+// Decode decodes data into v with encoding/json. Documents holding
+// Infinity or NaN, which JSON cannot represent, are decoded through a tree
+// instead.
+func Decode(data []byte, v any) error {
+	r, err := standardize(data)
+	if err != nil {
+		return err
+	}
+	if len(r.nonFinite) > 0 {
+		n, err := r.node(data)
+		if err != nil {
+			return err
+		}
+		return n.Decode(v)
+	}
+	return r.wrap(data, json.Unmarshal(r.out, v))
+}
+
+// DecodeNode decodes data into a tree that keeps the order of object keys,
+// distinguishes integers from floats and records the position of each
+// value in data.
+func DecodeNode(data []byte) (*conf.Node, error) {
+	r, err := standardize(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.node(data)
+}
+
+// result is data rewritten as standard JSON.
+type result struct {
+	out []byte
+
+	// spans records the output offsets from which output offsets map to
+	// input offsets with a new difference.
+	spans []span
+
+	// nonFinite holds Infinity and NaN by the output offset of the null
+	// standing in for them.
+	nonFinite map[int]float64
+}
+
+type span struct {
+	out, in int
+}
+
+// mark records that the output written next comes from input offset in.
+func (r *result) mark(in int) {
+	if n := len(r.spans); n > 0 && r.spans[n-1].in-r.spans[n-1].out == in-len(r.out) {
+		return
+	}
+	r.spans = append(r.spans, span{out: len(r.out), in: in})
+}
+
+// original returns the input offset of output offset off.
+func (r *result) original(off int) int {
+	i := sort.Search(len(r.spans), func(i int) bool { return r.spans[i].out > off }) - 1
+	if i < 0 {
+		return off
+	}
+	return r.spans[i].in + off - r.spans[i].out
+}
+
+// node decodes the output into a tree with positions in data.
+func (r *result) node(data []byte) (*conf.Node, error) {
+	n := &conf.Node{}
+	if err := n.UnmarshalJSON(r.out); err != nil {
+		return nil, r.wrap(data, err)
+	}
+	in, out := textpos.NewIndex(data), textpos.NewIndex(r.out)
+	var walk func(n *conf.Node)
+	walk = func(n *conf.Node) {
+		if n.Line > 0 {
+			off := out.Offset(n.Line, n.Column)
+			if f, ok := r.nonFinite[off]; ok {
+				n.Kind, n.Value = conf.FloatNode, f
+			}
+			n.Line, n.Column = in.Position(r.original(off))
+		}
+		for _, f := range n.Fields {
+			walk(f.Value)
+		}
+		for _, item := range n.Items {
+			walk(item)
+		}
+	}
+	walk(n)
+	return n, nil
+}
+
+// wrap gives the position in data of a syntax error in the output.
+func (r *result) wrap(data []byte, err error) error {
+	var se *json.SyntaxError
+	if !errors.As(err, &se) {
+		return err
+	}
+	off := r.original(int(min(max(se.Offset-1, 0), int64(len(r.out)))))
+	line, col := textpos.NewIndex(data).Position(off)
+	return fmt.Errorf("json5: line %d, column %d: %w", line, col, err)
+}
+
+// standardize rewrites data into standard JSON. Comments are replaced by
+// whitespace so that line numbers are preserved.
+func standardize(data []byte) (*result, error) {
+	s := &scanner{data: data}
+	r := &result{out: make([]byte, 0, len(data))}
+	for s.pos < len(data) {
+		r.mark(s.pos)
+		c := data[s.pos]
+		switch {
+		case c == '/':
+			start := s.pos
+			if err := s.skipComment(); err != nil {
+				return nil, err
+			}
+			r.out = appendBlank(r.out, data[start:s.pos])
+		case c == '"' || c == '\'':
+			str, err := s.readString(c)
+			if err != nil {
+				return nil, err
+			}
+			r.out = append(r.out, str...)
+		case c == ',':
+			s.pos++
+			next, err := s.peek()
+			if err != nil {
+				return nil, err
+			}
+			// A trailing comma must follow an element. Others are kept
+			// for the JSON decoder to reject.
+			if (next == '}' || next == ']') && !r.afterOpen() {
+				r.out = append(r.out, ' ')
+			} else {
+				r.out = append(r.out, ',')
+			}
+		case c >= '0' && c <= '9' || c == '.' || c == '+' || c == '-':
+			if err := s.readNumber(r); err != nil {
+				return nil, err
+			}
+		case isIdentStart(c) && s.space() == 0:
+			ident := s.readIdent()
+			next, err := s.peek()
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case next == ':':
+				r.out = append(r.out, '"')
+				r.out = append(r.out, ident...)
+				r.out = append(r.out, '"')
+			case string(ident) == "Infinity":
+				r.addNonFinite(math.Inf(1))
+			case string(ident) == "NaN":
+				r.addNonFinite(math.NaN())
+			default:
+				r.out = append(r.out, ident...)
+			}
+		default:
+			if n := s.space(); n > 0 {
+				r.out = append(r.out, bytes.Repeat([]byte{' '}, n)...)
+				s.pos += n
+				continue
+			}
+			r.out = append(r.out, c)
+			s.pos++
+		}
+	}
+	return r, nil
+}
+
+// afterOpen reports whether the last significant output byte opens an
+// object or array.
+func (r *result) afterOpen() bool {
+	out := bytes.TrimRight(r.out, " \t\r\n")
+	return len(out) > 0 && (out[len(out)-1] == '{' || out[len(out)-1] == '[')
+}
+
+// addNonFinite writes a null standing in for f.
+func (r *result) addNonFinite(f float64) {
+	if r.nonFinite == nil {
+		r.nonFinite = make(map[int]float64)
+	}
+	r.nonFinite[len(r.out)] = f
+	r.out = append(r.out, "null"...)
+}
+
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+// space returns the length of the JSON5 whitespace that JSON lacks at the
+// current position: vertical tab, form feed, no-break space or byte order
+// mark. It returns 0 for anything else.
+func (s *scanner) space() int {
+	rest := s.data[s.pos:]
+	switch {
+	case len(rest) == 0:
+		return 0
+	case rest[0] == '\v' || rest[0] == '\f':
+		return 1
+	case bytes.HasPrefix(rest, []byte("\u00a0")):
+		return 2
+	case bytes.HasPrefix(rest, []byte("\ufeff")):
+		return 3
+	}
+	return 0
+}
+
+// skipComment advances past the comment starting at the current position.
+func (s *scanner) skipComment() error {
+	if s.pos+1 >= len(s.data) {
+		return s.errorf("unexpected '/'")
+	}
+	switch s.data[s.pos+1] {
+	case '/':
+		end := bytes.IndexByte(s.data[s.pos:], '\n')
+		if end < 0 {
+			s.pos = len(s.data)
+		} else {
+			s.pos += end
+		}
+	case '*':
+		end := bytes.Index(s.data[s.pos+2:], []byte("*/"))
+		if end < 0 {
+			return s.errorf("unterminated block comment")
+		}
+		s.pos += end + 4
+	default:
+		return s.errorf("unexpected '/'")
+	}
+	return nil
+}
+
+// peek returns the next significant byte after whitespace and comments
+// without consuming it. It returns 0 at the end of input.
+func (s *scanner) peek() (byte, error) {
+	saved := s.pos
+	defer func() { s.pos = saved }()
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		case '/':
+			if err := s.skipComment(); err != nil {
+				return 0, err
+			}
+		default:
+			if n := s.space(); n > 0 {
+				s.pos += n
+				continue
+			}
+			return s.data[s.pos], nil
+		}
+	}
+	return 0, nil
+}
+
+// readString consumes a quoted string and returns it as a double-quoted
+// JSON string literal.
+func (s *scanner) readString(quote byte) ([]byte, error) {
+	start := s.pos
+	out := []byte{'"'}
+	s.pos++
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == quote:
+			s.pos++
+			return append(out, '"'), nil
+		case c == '\\':
+			if s.pos+1 >= len(s.data) {
+				s.pos = start
+				return nil, s.errorf("unterminated string")
+			}
+			var err error
+			if out, err = s.readEscape(out); err != nil {
+				return nil, err
+			}
+			continue
+		case c == '"':
+			out = append(out, '\\', '"')
+		case c == '\n' || c == '\r':
+			return nil, s.errorf("newline in string")
+		case c < 0x20:
+			out = fmt.Appendf(out, `\u%04x`, c)
+		default:
+			out = append(out, c)
+		}
+		s.pos++
+	}
+	s.pos = start
+	return nil, s.errorf("unterminated string")
+}
+
+// readEscape consumes the escape sequence at the current position and
+// appends its JSON equivalent to out.
+func (s *scanner) readEscape(out []byte) ([]byte, error) {
+	next := s.data[s.pos+1]
+	s.pos += 2
+	switch next {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't', 'u':
+		return append(out, '\\', next), nil
+	case '\'':
+		return append(out, '\''), nil
+	case 'v':
+		return append(out, `\u000b`...), nil
+	case '0':
+		if s.pos < len(s.data) && isDigit(s.data[s.pos]) {
+			s.pos -= 2
+			return nil, s.errorf("invalid escape")
+		}
+		return append(out, `\u0000`...), nil
+	case 'x':
+		if s.pos+2 > len(s.data) || !isHex(s.data[s.pos]) || !isHex(s.data[s.pos+1]) {
+			s.pos -= 2
+			return nil, s.errorf("invalid escape")
+		}
+		out = append(out, `\u00`...)
+		out = append(out, s.data[s.pos:s.pos+2]...)
+		s.pos += 2
+		return out, nil
+	case '\n':
+		// Line continuation.
+		return out, nil
+	case '\r':
+		if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+			s.pos++
+		}
+		return out, nil
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		s.pos -= 2
+		return nil, s.errorf("invalid escape")
+	}
+	// Line continuation with a line or paragraph separator.
+	if rest := s.data[s.pos-1:]; bytes.HasPrefix(rest, []byte("\u2028")) || bytes.HasPrefix(rest, []byte("\u2029")) {
+		s.pos += 2
+		return out, nil
+	}
+	// Any other escaped character stands for itself; the rest of a
+	// multi-byte character is copied by the caller.
+	return append(out, next), nil
+}
+
+// readNumber consumes a JSON5 number and writes it as a JSON number, or as
+// a null standing in for Infinity or NaN.
+func (s *scanner) readNumber(r *result) error {
+	start := s.pos
+	neg := false
+	if c := s.data[s.pos]; c == '+' || c == '-' {
+		neg = c == '-'
+		s.pos++
+	}
+	if s.pos < len(s.data) && isIdentStart(s.data[s.pos]) {
+		switch string(s.readIdent()) {
+		case "Infinity":
+			if neg {
+				r.addNonFinite(math.Inf(-1))
+			} else {
+				r.addNonFinite(math.Inf(1))
+			}
+			return nil
+		case "NaN":
+			r.addNonFinite(math.NaN())
+			return nil
+		}
+		s.pos = start
+		return s.errorf("invalid number")
+	}
+
+	if s.pos+1 < len(s.data) && s.data[s.pos] == '0' && s.data[s.pos+1]|0x20 == 'x' {
+		s.pos += 2
+		digits := s.pos
+		for s.pos < len(s.data) && isHex(s.data[s.pos]) {
+			s.pos++
+		}
+		var i big.Int
+		if _, ok := i.SetString(string(s.data[digits:s.pos]), 16); !ok || s.identFollows() {
+			s.pos = start
+			return s.errorf("invalid number")
+		}
+		if neg {
+			i.Neg(&i)
+		}
+		r.out = append(r.out, i.String()...)
+		return nil
+	}
+
+	intPart := s.digits()
+	var frac []byte
+	dot := s.pos < len(s.data) && s.data[s.pos] == '.'
+	if dot {
+		s.pos++
+		frac = s.digits()
+	}
+	var exp []byte
+	if s.pos < len(s.data) && s.data[s.pos]|0x20 == 'e' {
+		e := s.pos
+		s.pos++
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		if len(s.digits()) == 0 {
+			s.pos = start
+			return s.errorf("invalid number")
+		}
+		exp = s.data[e:s.pos]
+	}
+	if len(intPart) == 0 && len(frac) == 0 || s.identFollows() {
+		s.pos = start
+		return s.errorf("invalid number")
+	}
+
+	if neg {
+		r.out = append(r.out, '-')
+	}
+	if len(intPart) == 0 {
+		intPart = []byte{'0'}
+	}
+	r.out = append(r.out, intPart...)
+	if dot {
+		if len(frac) == 0 {
+			frac = []byte{'0'}
+		}
+		r.out = append(r.out, '.')
+		r.out = append(r.out, frac...)
+	}
+	r.out = append(r.out, exp...)
+	return nil
+}
+
+// digits consumes and returns a run of decimal digits.
+func (s *scanner) digits() []byte {
+	start := s.pos
+	for s.pos < len(s.data) && isDigit(s.data[s.pos]) {
+		s.pos++
+	}
+	return s.data[start:s.pos]
+}
+
+// identFollows reports whether an identifier character is at the current
+// position, as in the malformed number 12ab.
+func (s *scanner) identFollows() bool {
+	return s.pos < len(s.data) && isIdentPart(s.data[s.pos])
+}
+
+func (s *scanner) readIdent() []byte {
+	start := s.pos
+	for s.pos < len(s.data) && isIdentPart(s.data[s.pos]) {
+		s.pos++
+	}
+	return s.data[start:s.pos]
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	line, col := textpos.NewIndex(s.data).Position(s.pos)
+	return fmt.Errorf("json5: line %d, column %d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+// appendBlank appends whitespace covering src, keeping its line breaks.
+func appendBlank(out, src []byte) []byte {
+	for _, c := range src {
+		if c == '\n' {
+			out = append(out, '\n')
+		} else {
+			out = append(out, ' ')
+		}
+	}
+	return out
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c|0x20 >= 'a' && c|0x20 <= 'f'
+}
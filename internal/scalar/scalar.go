@@ -0,0 +1,46 @@
+// Package scalar formats scalar values consistently across codecs that
+// write conf.Node trees.
+package scalar
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatFloat formats f in its shortest representation, always including
+// a decimal point or exponent so that it is not read back as an integer.
+func FormatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.ContainsAny(s, "eE") {
+		// Match encoding/json, which uses exponents only for very large
+		// or small magnitudes.
+		if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	if !strings.ContainsAny(s, ".eEIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// LocalDate is the location of times that hold a date without a time of
+// day, such as a YAML date, named like the one the TOML library uses.
+var LocalDate = time.FixedZone("date-local", 0)
+
+// FormatTime formats t as RFC 3339. Local dates and times without an
+// offset, which the TOML library represents with the locations
+// "date-local", "time-local" and "datetime-local", keep that form.
+func FormatTime(t time.Time) string {
+	switch t.Location().String() {
+	case "date-local":
+		return t.Format(time.DateOnly)
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,31 @@
+// Package textpos converts byte offsets in a document to line and column
+// numbers.
+package textpos
+
+import "sort"
+
+// Index records where each line of a document starts.
+type Index []int
+
+// NewIndex indexes the lines of src.
+func NewIndex(src []byte) Index {
+	ix := Index{0}
+	for i, c := range src {
+		if c == '\n' {
+			ix = append(ix, i+1)
+		}
+	}
+	return ix
+}
+
+// Position returns the 1-based line and byte column of offset off.
+func (ix Index) Position(off int) (line, column int) {
+	l := sort.SearchInts(ix, off+1) - 1
+	return l + 1, off - ix[l] + 1
+}
+
+// Offset returns the offset of the 1-based line and byte column, the
+// inverse of Position.
+func (ix Index) Offset(line, column int) int {
+	return ix[line-1] + column - 1
+}
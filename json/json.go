@@ -5,32 +5,171 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
+	"slices"
+	"strings"
 
 	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/json5"
 )
 
 func init() {
 	conf.Register("json", New())
 }
 
-// New returns a new JSON codec.
-func New() conf.Codec {
-	return &jsonCodec{}
+// Option configures a JSON codec created by New.
+type Option func(*jsonCodec)
+
+// AllowComments makes the codec accept JSONC and JSON5 when decoding, as
+// the jsonc codec does: comments, trailing commas, unquoted keys and the
+// JSON5 strings and numbers. Encoding is unaffected.
+func AllowComments() Option {
+	return func(c *jsonCodec) {
+		c.allowComments = true
+	}
+}
+
+// Indent sets the string used for each indentation level when encoding.
+// The default is two spaces.
+func Indent(indent string) Option {
+	return func(c *jsonCodec) {
+		c.indent = indent
+		c.compact = false
+	}
+}
+
+// Compact makes Encode write JSON without any insignificant whitespace.
+func Compact() Option {
+	return func(c *jsonCodec) {
+		c.compact = true
+	}
+}
+
+// EscapeHTML sets whether Encode escapes the characters <, > and & in
+// strings. It is enabled by default, as in encoding/json.
+func EscapeHTML(on bool) Option {
+	return func(c *jsonCodec) {
+		c.escapeHTML = on
+	}
+}
+
+// SortKeys makes Encode write the keys of every object in sorted order,
+// including those of structs, whose fields are otherwise written in
+// declaration order. Map keys are always sorted.
+func SortKeys() Option {
+	return func(c *jsonCodec) {
+		c.sortKeys = true
+	}
 }
 
-type jsonCodec struct{}
+// New returns a new JSON codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &jsonCodec{indent: "  ", escapeHTML: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type jsonCodec struct {
+	indent        string
+	compact       bool
+	escapeHTML    bool
+	sortKeys      bool
+	allowComments bool
+}
 
 func (c *jsonCodec) Encode(v any) ([]byte, error) {
-	return json.MarshalIndent(v, "", "  ")
+	if c.sortKeys {
+		n, err := conf.NewNode(v)
+		if err != nil {
+			return nil, err
+		}
+		v = sorted(n)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(c.escapeHTML)
+	if !c.compact {
+		encoder.SetIndent("", c.indent)
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encoder terminates each value with a newline; Marshal does not.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// sorted returns a copy of n with the fields of every map sorted by key.
+func sorted(n *conf.Node) *conf.Node {
+	out := *n
+	switch n.Kind {
+	case conf.MapNode:
+		out.Fields = make([]conf.Field, len(n.Fields))
+		for i, f := range n.Fields {
+			out.Fields[i] = conf.Field{Key: f.Key, Value: sorted(f.Value)}
+		}
+		slices.SortStableFunc(out.Fields, func(a, b conf.Field) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+	case conf.ListNode:
+		out.Items = make([]*conf.Node, len(n.Items))
+		for i, item := range n.Items {
+			out.Items[i] = sorted(item)
+		}
+	}
+	return &out
 }
 
 func (c *jsonCodec) Decode(data []byte, v any) error {
+	if c.allowComments {
+		return json5.Decode(data, v)
+	}
 	return json.Unmarshal(data, v)
 }
 
+// DecodeNode decodes data into a tree that keeps the order of object keys
+// and distinguishes integers from floats.
+func (c *jsonCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	if c.allowComments {
+		return json5.DecodeNode(data)
+	}
+	n := &conf.Node{}
+	if err := n.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// EncodeNode encodes n with the codec's formatting options.
+func (c *jsonCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	return c.Encode(n)
+}
+
+// Sniff reports whether data is a valid JSON object or array.
+func (c *jsonCodec) Sniff(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[') && json.Valid(data)
+}
+
 func (c *jsonCodec) Extensions() []string {
 	return []string{".json"}
 }
 
-var _ conf.Codec = (*jsonCodec)(nil)
+// Edit parses data into a document that can be modified in place,
+// preserving comments and formatting.
+func (c *jsonCodec) Edit(data []byte) (conf.EditableDocument, error) {
+	doc, err := json5.Edit(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+var (
+	_ conf.Codec     = (*jsonCodec)(nil)
+	_ conf.Editor    = (*jsonCodec)(nil)
+	_ conf.NodeCodec = (*jsonCodec)(nil)
+	_ conf.Sniffer   = (*jsonCodec)(nil)
+)
@@ -25,3 +25,75 @@ func TestJSONRegistration(t *testing.T) {
 		t.Error("json should be registered via init()")
 	}
 }
+
+func TestJSONAllowComments(t *testing.T) {
+	input := []byte("{\n  // listen port\n  \"port\": 8080,\n}\n")
+
+	var v struct {
+		Port int `json:"port"`
+	}
+	if err := cj.New().Decode(input, &v); err == nil {
+		t.Error("expected default codec to reject comments")
+	}
+	if err := cj.New(cj.AllowComments()).Decode(input, &v); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if v.Port != 8080 {
+		t.Errorf("Port: got %d, want %d", v.Port, 8080)
+	}
+}
+
+func TestJSONEncodeOptions(t *testing.T) {
+	v := map[string]any{"a": "<b>", "n": 1}
+
+	tests := []struct {
+		name string
+		opts []cj.Option
+		want string
+	}{
+		{"default", nil, "{\n  \"a\": \"\\u003cb\\u003e\",\n  \"n\": 1\n}"},
+		{"indent", []cj.Option{cj.Indent("\t")}, "{\n\t\"a\": \"\\u003cb\\u003e\",\n\t\"n\": 1\n}"},
+		{"compact", []cj.Option{cj.Compact()}, `{"a":"\u003cb\u003e","n":1}`},
+		{"no escape", []cj.Option{cj.Compact(), cj.EscapeHTML(false)}, `{"a":"<b>","n":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := cj.New(tt.opts...).Encode(v)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONSortKeys(t *testing.T) {
+	type inner struct {
+		Z int `json:"z"`
+		A int `json:"a"`
+	}
+	v := struct {
+		Name  string  `json:"name"`
+		Items []inner `json:"items"`
+		Inner inner   `json:"inner"`
+	}{Name: "<x>", Items: []inner{{1, 2}}, Inner: inner{3, 4}}
+
+	data, err := cj.New(cj.Compact(), cj.SortKeys(), cj.EscapeHTML(false)).Encode(v)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := `{"inner":{"a":4,"z":3},"items":[{"a":2,"z":1}],"name":"<x>"}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	data, err = cj.New(cj.Compact()).Encode(v)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := `{"name":"\u003cx\u003e","items":[{"z":1,"a":2}],"inner":{"z":3,"a":4}}`; string(data) != want {
+		t.Errorf("without SortKeys got %s, want %s", data, want)
+	}
+}
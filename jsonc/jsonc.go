@@ -0,0 +1,74 @@
+// Package jsonc provides a codec for human-edited JSON (JSONC and JSON5)
+// for the conf package. Import this package to register the "jsonc" codec:
+//
+//	import _ "github.com/nuln/conf/jsonc"
+//
+// Decoding accepts line and block comments, trailing commas, unquoted
+// object keys, single-quoted and multi-line strings, hexadecimal numbers,
+// numbers with a leading or trailing decimal point or a plus sign,
+// Infinity and NaN. Encoding produces standard, indented JSON, which is
+// valid in both dialects.
+package jsonc
+
+import (
+	"encoding/json"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/json5"
+)
+
+func init() {
+	conf.Register("jsonc", New())
+}
+
+// New returns a new JSONC codec.
+func New() conf.Codec {
+	return &jsoncCodec{}
+}
+
+type jsoncCodec struct{}
+
+func (c *jsoncCodec) Encode(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (c *jsoncCodec) Decode(data []byte, v any) error {
+	return json5.Decode(data, v)
+}
+
+// DecodeNode decodes data into a tree that keeps the order of object keys
+// and distinguishes integers from floats.
+func (c *jsoncCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	return json5.DecodeNode(data)
+}
+
+func (c *jsoncCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	return c.Encode(n)
+}
+
+// Sniff reports whether data is a JSONC or JSON5 object or array.
+func (c *jsoncCodec) Sniff(data []byte) bool {
+	n, err := json5.DecodeNode(data)
+	return err == nil && (n.Kind == conf.MapNode || n.Kind == conf.ListNode)
+}
+
+func (c *jsoncCodec) Extensions() []string {
+	return []string{".jsonc", ".json5"}
+}
+
+// Edit parses data into a document that can be modified in place,
+// preserving comments and formatting.
+func (c *jsoncCodec) Edit(data []byte) (conf.EditableDocument, error) {
+	doc, err := json5.Edit(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+var (
+	_ conf.Codec     = (*jsoncCodec)(nil)
+	_ conf.Editor    = (*jsoncCodec)(nil)
+	_ conf.NodeCodec = (*jsoncCodec)(nil)
+	_ conf.Sniffer   = (*jsoncCodec)(nil)
+)
@@ -0,0 +1,196 @@
+package jsonc_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cj "github.com/nuln/conf/jsonc"
+)
+
+func TestJSONC(t *testing.T) {
+	conftest.Suite(t, cj.New())
+}
+
+func TestJSONCRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "jsonc" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("jsonc should be registered via init()")
+	}
+}
+
+func TestJSONCRelaxedSyntax(t *testing.T) {
+	input := []byte(`// service settings
+{
+	name: 'my "app"', /* inline */
+	port: 8080,
+	tags: ['web', "api",],
+	database: {
+		host: 'db.local', // trailing comment
+	},
+}
+`)
+
+	var cfg struct {
+		Name     string   `json:"name"`
+		Port     int      `json:"port"`
+		Tags     []string `json:"tags"`
+		Database struct {
+			Host string `json:"host"`
+		} `json:"database"`
+	}
+	if err := cj.New().Decode(input, &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if cfg.Name != `my "app"` {
+		t.Errorf("Name: got %q", cfg.Name)
+	}
+	if cfg.Port != 8080 {
+		t.Errorf("Port: got %d", cfg.Port)
+	}
+	if len(cfg.Tags) != 2 || cfg.Tags[0] != "web" || cfg.Tags[1] != "api" {
+		t.Errorf("Tags: got %v", cfg.Tags)
+	}
+	if cfg.Database.Host != "db.local" {
+		t.Errorf("Database.Host: got %q", cfg.Database.Host)
+	}
+}
+
+func TestJSONCUnterminatedComment(t *testing.T) {
+	var v map[string]any
+	if err := cj.New().Decode([]byte(`{"a": 1 /* oops`), &v); err == nil {
+		t.Error("expected error for unterminated block comment")
+	}
+}
+
+func TestJSONCEdit(t *testing.T) {
+	input := `// service settings
+{
+    "name": "billing", // display name
+    "database": {
+        "host": "localhost",
+        /* connection pool */
+        "pool": 10
+    }
+}
+`
+	want := `// service settings
+{
+    "name": "payments", // display name
+    "database": {
+        "host": "localhost",
+        "user": "admin"
+    }
+}
+`
+
+	doc, err := cj.New().(conf.Editor).Edit([]byte(input))
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if err := doc.Set([]string{"name"}, "payments"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Delete([]string{"database", "pool"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := doc.Set([]string{"database", "user"}, "admin"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestJSONCJSON5Grammar(t *testing.T) {
+	input := []byte(`{
+	hex: 0x1F, neg: -0xff, lead: .5, trail: 5., plus: +1, exp: 2.e3,
+	inf: Infinity, ninf: -Infinity, nan: NaN,
+	multi: 'one \
+two', escapes: '\x41\v\0\q\'',
+	tab: "a	b",
+}`)
+
+	var cfg struct {
+		Hex     int     `json:"hex"`
+		Neg     int     `json:"neg"`
+		Lead    float64 `json:"lead"`
+		Trail   float64 `json:"trail"`
+		Plus    int     `json:"plus"`
+		Exp     float64 `json:"exp"`
+		Inf     float64 `json:"inf"`
+		NInf    float64 `json:"ninf"`
+		NaN     float64 `json:"nan"`
+		Multi   string  `json:"multi"`
+		Escapes string  `json:"escapes"`
+		Tab     string  `json:"tab"`
+	}
+	if err := cj.New().Decode(input, &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Hex != 31 || cfg.Neg != -255 || cfg.Lead != 0.5 || cfg.Trail != 5 || cfg.Plus != 1 || cfg.Exp != 2000 {
+		t.Errorf("numbers: got %+v", cfg)
+	}
+	if !math.IsInf(cfg.Inf, 1) || !math.IsInf(cfg.NInf, -1) || !math.IsNaN(cfg.NaN) {
+		t.Errorf("non-finite numbers: got %v, %v, %v", cfg.Inf, cfg.NInf, cfg.NaN)
+	}
+	if cfg.Multi != "one two" || cfg.Escapes != "A\v\x00q'" || cfg.Tab != "a\tb" {
+		t.Errorf("strings: got %q, %q, %q", cfg.Multi, cfg.Escapes, cfg.Tab)
+	}
+
+	var trailing struct {
+		A []int          `json:"a"`
+		B map[string]int `json:"b"`
+	}
+	if err := cj.New().Decode([]byte(`{a: [1,], b: {c: 1,}, }`), &trailing); err != nil {
+		t.Errorf("trailing commas: %v", err)
+	}
+
+	n, err := cj.New().(conf.NodeCodec).DecodeNode([]byte(`{a: 0x10, b: +Infinity}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := n.Get("a"); a.Kind != conf.IntNode || a.Value != int64(16) {
+		t.Errorf("a: got %v %v", a.Kind, a.Value)
+	}
+	if b := n.Get("b"); b.Kind != conf.FloatNode || !math.IsInf(b.Value.(float64), 1) {
+		t.Errorf("b: got %v %v", b.Kind, b.Value)
+	}
+
+	for _, bad := range []string{`{a: 0x}`, `{a: 12ab}`, `{a: .}`, `{a: -foo}`, `{a: '\1'}`, `{a: '\xZ1'}`, `{a: [,]}`, `{,}`, `{a: [1,,]}`} {
+		var v map[string]any
+		if err := cj.New().Decode([]byte(bad), &v); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestJSONCPositions(t *testing.T) {
+	n, err := cj.New().(conf.NodeCodec).DecodeNode([]byte("{a: 1, b: 0x10, c: 3,\n  'd': Infinity}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]int{"a": {1, 5}, "b": {1, 11}, "c": {1, 20}, "d": {2, 8}}
+	for key, pos := range want {
+		if v := n.Get(key); v.Line != pos[0] || v.Column != pos[1] {
+			t.Errorf("%s: got %d:%d, want %d:%d", key, v.Line, v.Column, pos[0], pos[1])
+		}
+	}
+
+	var v map[string]any
+	err = cj.New().Decode([]byte("{a: 1, b: ]}"), &v)
+	if err == nil || !strings.Contains(err.Error(), "line 1, column 11") {
+		t.Errorf("expected an error at line 1, column 11, got: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package jsonl provides a JSON Lines (newline-delimited JSON) codec for
+// the conf package. Import this package to register the "jsonl" codec:
+//
+//	import _ "github.com/nuln/conf/jsonl"
+//
+// Each line holds one JSON record. Decode replaces the target with the
+// records when it points to a slice, and otherwise decodes the single
+// record the data must contain. Encode writes one line per element of a
+// slice or array and a single line for any other value. Use conf.LoadAll
+// to process records one at a time.
+package jsonl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/textpos"
+)
+
+func init() {
+	conf.Register("jsonl", New())
+}
+
+// New returns a new JSON Lines codec.
+func New() conf.Codec {
+	return &jsonlCodec{}
+}
+
+type jsonlCodec struct{}
+
+func (c *jsonlCodec) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := range rv.Len() {
+			if err := encoder.Encode(rv.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *jsonlCodec) Decode(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("jsonl: decode target must be a non-nil pointer, got %T", v)
+	}
+
+	if t := rv.Elem().Type(); t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		records := reflect.MakeSlice(t, 0, 0)
+		err := c.DecodeAll(data, func(decode func(v any) error) error {
+			elem := reflect.New(t.Elem())
+			if err := decode(elem.Interface()); err != nil {
+				return err
+			}
+			records = reflect.Append(records, elem.Elem())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		rv.Elem().Set(records)
+		return nil
+	}
+
+	n := 0
+	err := c.DecodeAll(data, func(decode func(v any) error) error {
+		if n++; n > 1 {
+			return errors.New("jsonl: multiple records cannot be decoded into a single value")
+		}
+		return decode(v)
+	})
+	if err == nil && n == 0 {
+		return errors.New("jsonl: no records")
+	}
+	return err
+}
+
+// DecodeAll calls fn for each record.
+func (c *jsonlCodec) DecodeAll(data []byte, fn func(decode func(v any) error) error) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		err := fn(func(v any) error {
+			return json.Unmarshal(raw, v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// DecodeNode decodes the records in data into a list.
+func (c *jsonlCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	list := &conf.Node{Kind: conf.ListNode, Items: []*conf.Node{}}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	index := textpos.NewIndex(data)
+	for {
+		off := int(decoder.InputOffset())
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return list, nil
+			}
+			return nil, err
+		}
+		record := &conf.Node{}
+		if err := record.UnmarshalJSON(raw); err != nil {
+			return nil, err
+		}
+		// Positions within a record are relative to its first line.
+		for off < len(data) && bytes.IndexByte([]byte(" \t\r\n"), data[off]) >= 0 {
+			off++
+		}
+		line, _ := index.Position(off)
+		shiftLines(record, line-1)
+		list.Items = append(list.Items, record)
+	}
+}
+
+func shiftLines(n *conf.Node, delta int) {
+	n.Line += delta
+	for _, f := range n.Fields {
+		shiftLines(f.Value, delta)
+	}
+	for _, item := range n.Items {
+		shiftLines(item, delta)
+	}
+}
+
+// EncodeNode writes one record per item of a list, or n itself as a single
+// record otherwise.
+func (c *jsonlCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	if n.Kind != conf.ListNode {
+		return c.Encode(n)
+	}
+	return c.Encode(n.Items)
+}
+
+func (c *jsonlCodec) Extensions() []string {
+	return []string{".jsonl", ".ndjson"}
+}
+
+var (
+	_ conf.Codec        = (*jsonlCodec)(nil)
+	_ conf.MultiDecoder = (*jsonlCodec)(nil)
+	_ conf.NodeCodec    = (*jsonlCodec)(nil)
+)
@@ -0,0 +1,107 @@
+package jsonl_test
+
+import (
+	"testing"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cj "github.com/nuln/conf/jsonl"
+)
+
+func TestJSONL(t *testing.T) {
+	conftest.Suite(t, cj.New())
+}
+
+func TestJSONLRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "jsonl" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("jsonl should be registered via init()")
+	}
+}
+
+type record struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+func TestJSONLSlice(t *testing.T) {
+	original := []record{{"a", 1}, {"b", 2}}
+
+	data, err := cj.New().Encode(original)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "{\"name\":\"a\",\"port\":1}\n{\"name\":\"b\",\"port\":2}\n"; string(data) != want {
+		t.Errorf("Encode: got %q, want %q", data, want)
+	}
+
+	var decoded []record
+	if err := cj.New().Decode(data, &decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(decoded) != 2 || decoded[1] != original[1] {
+		t.Errorf("Decode: got %+v", decoded)
+	}
+
+	decoded = []record{{"default", 0}}
+	if err := cj.New().Decode(data, &decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(decoded) != 2 || decoded[0] != original[0] {
+		t.Errorf("Decode into a non-empty slice: got %+v", decoded)
+	}
+
+	var single record
+	if err := cj.New().Decode(data, &single); err == nil {
+		t.Error("expected error decoding multiple records into a single value")
+	}
+}
+
+func TestJSONLLoadAll(t *testing.T) {
+	data := []byte("{\"name\":\"a\",\"port\":1}\n\n{\"name\":\"b\",\"port\":2}\n")
+
+	var names []string
+	err := conf.LoadAllFromBytes(data, "jsonl", func(i int, decode func(v any) error) error {
+		var r record
+		if err := decode(&r); err != nil {
+			return err
+		}
+		names = append(names, r.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("LoadAllFromBytes failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("got %v", names)
+	}
+}
+
+func TestJSONLNode(t *testing.T) {
+	nc := cj.New().(conf.NodeCodec)
+	n, err := nc.DecodeNode([]byte("{\"b\": 1, \"a\": 2}\n\n{\"b\": 3.5}\n"))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	if len(n.Items) != 2 {
+		t.Fatalf("got %d records, want 2", len(n.Items))
+	}
+	if b := n.Items[1].Get("b"); b.Kind != conf.FloatNode || b.Line != 3 {
+		t.Errorf("second record: got %s at line %d", b.Kind, b.Line)
+	}
+
+	data, err := nc.EncodeNode(n)
+	if err != nil {
+		t.Fatalf("EncodeNode failed: %v", err)
+	}
+	if want := "{\"b\":1,\"a\":2}\n{\"b\":3.5}\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
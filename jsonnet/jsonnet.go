@@ -0,0 +1,180 @@
+// Package jsonnet provides a Jsonnet codec for the conf package.
+// Import this package to register the "jsonnet" codec:
+//
+//	import _ "github.com/nuln/conf/jsonnet"
+//
+// Decode evaluates the Jsonnet program and decodes the resulting JSON into
+// the target value, so templated configuration is expanded inside
+// conf.Load. External variables, top-level arguments and library search
+// paths are supplied through options:
+//
+//	conf.Register("jsonnet", jsonnet.New(
+//	    jsonnet.ExtVar("env", "prod"),
+//	    jsonnet.TLACode("replicas", "3"),
+//	    jsonnet.ImportPaths("lib"),
+//	))
+//
+// Imports are resolved relative to the file being loaded, then in the
+// import paths. Encode writes indented JSON, which is a valid Jsonnet
+// program.
+package jsonnet
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/go-jsonnet"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.Register("jsonnet", New())
+}
+
+// Option configures a Jsonnet codec created by New.
+type Option func(*jsonnetCodec)
+
+// ExtVar binds the external variable name to the string value, available
+// to the program as std.extVar(name).
+func ExtVar(name, value string) Option {
+	return func(c *jsonnetCodec) {
+		c.setup = append(c.setup, func(vm *jsonnet.VM) { vm.ExtVar(name, value) })
+	}
+}
+
+// ExtCode binds the external variable name to the result of evaluating the
+// Jsonnet expression code.
+func ExtCode(name, code string) Option {
+	return func(c *jsonnetCodec) {
+		c.setup = append(c.setup, func(vm *jsonnet.VM) { vm.ExtCode(name, code) })
+	}
+}
+
+// TLA passes the string value as the top-level argument name when the
+// program evaluates to a function.
+func TLA(name, value string) Option {
+	return func(c *jsonnetCodec) {
+		c.setup = append(c.setup, func(vm *jsonnet.VM) { vm.TLAVar(name, value) })
+	}
+}
+
+// TLACode passes the result of evaluating the Jsonnet expression code as
+// the top-level argument name.
+func TLACode(name, code string) Option {
+	return func(c *jsonnetCodec) {
+		c.setup = append(c.setup, func(vm *jsonnet.VM) { vm.TLACode(name, code) })
+	}
+}
+
+// ImportPaths adds library search directories used to resolve import
+// statements, in order of precedence.
+func ImportPaths(dirs ...string) Option {
+	return func(c *jsonnetCodec) {
+		c.importPaths = append(c.importPaths, dirs...)
+	}
+}
+
+// New returns a new Jsonnet codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &jsonnetCodec{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type jsonnetCodec struct {
+	setup       []func(*jsonnet.VM)
+	importPaths []string
+	filename    string // file being decoded, if known
+}
+
+// ForFile returns a codec that evaluates the program in the file at path,
+// resolving its imports relative to it and naming it in errors.
+func (c *jsonnetCodec) ForFile(path string) conf.Codec {
+	fc := *c
+	fc.filename = path
+	return &fc
+}
+
+func (c *jsonnetCodec) Encode(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (c *jsonnetCodec) Decode(data []byte, v any) error {
+	out, err := c.evaluate(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(out, v)
+}
+
+// DecodeNode evaluates data into a tree. Fields are in the sorted order of
+// Jsonnet's output, and integers stay distinct from floats; positions
+// refer to the output rather than the program, so none are recorded.
+func (c *jsonnetCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	out, err := c.evaluate(data)
+	if err != nil {
+		return nil, err
+	}
+	n := &conf.Node{}
+	if err := n.UnmarshalJSON(out); err != nil {
+		return nil, err
+	}
+	clearPositions(n)
+	return n, nil
+}
+
+func clearPositions(n *conf.Node) {
+	n.Line, n.Column = 0, 0
+	for _, f := range n.Fields {
+		clearPositions(f.Value)
+	}
+	for _, item := range n.Items {
+		clearPositions(item)
+	}
+}
+
+func (c *jsonnetCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	return c.Encode(n)
+}
+
+// evaluate runs the program in data and returns its JSON output.
+func (c *jsonnetCodec) evaluate(data []byte) ([]byte, error) {
+	// A VM caches imports and is not safe for concurrent use, so each
+	// evaluation gets its own.
+	vm := jsonnet.MakeVM()
+	// The importer tries the directory of the importing file first, then
+	// its paths from last to first.
+	var paths []string
+	filename := "<input>"
+	if c.filename != "" {
+		filename = c.filename
+		paths = append(paths, filepath.Dir(c.filename))
+	}
+	for _, dir := range slices.Backward(c.importPaths) {
+		paths = append(paths, dir)
+	}
+	vm.Importer(&jsonnet.FileImporter{JPaths: paths})
+	for _, setup := range c.setup {
+		setup(vm)
+	}
+
+	out, err := vm.EvaluateAnonymousSnippet(filename, string(data))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (c *jsonnetCodec) Extensions() []string {
+	return []string{".jsonnet", ".libsonnet"}
+}
+
+var (
+	_ conf.Codec     = (*jsonnetCodec)(nil)
+	_ conf.FileCodec = (*jsonnetCodec)(nil)
+	_ conf.NodeCodec = (*jsonnetCodec)(nil)
+)
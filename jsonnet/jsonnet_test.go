@@ -0,0 +1,98 @@
+package jsonnet_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cj "github.com/nuln/conf/jsonnet"
+)
+
+func TestJsonnet(t *testing.T) {
+	conftest.Suite(t, cj.New())
+}
+
+func TestJsonnetRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "jsonnet" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("jsonnet should be registered via init()")
+	}
+}
+
+func TestJsonnetEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	lib := []byte(`{ port(env):: if env == "prod" then 443 else 8080 }`)
+	if err := os.WriteFile(filepath.Join(dir, "ports.libsonnet"), lib, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	program := []byte(`
+local ports = import "ports.libsonnet";
+function(replicas) {
+  name: "svc-" + std.extVar("env"),
+  port: ports.port(std.extVar("env")),
+  replicas: replicas,
+}`)
+
+	codec := cj.New(
+		cj.ExtVar("env", "prod"),
+		cj.TLACode("replicas", "3"),
+		cj.ImportPaths(dir),
+	)
+
+	var cfg struct {
+		Name     string `json:"name"`
+		Port     int    `json:"port"`
+		Replicas int    `json:"replicas"`
+	}
+	if err := codec.Decode(program, &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Name != "svc-prod" || cfg.Port != 443 || cfg.Replicas != 3 {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+}
+
+func TestJsonnetLoadResolvesImportsRelativeToFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sub")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	lib := []byte(`{ port: 8443 }`)
+	if err := os.WriteFile(filepath.Join(dir, "lib.libsonnet"), lib, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "svc.jsonnet")
+	if err := os.WriteFile(path, []byte(`{ port: (import "lib.libsonnet").port }`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		Port int `json:"port"`
+	}
+	if err := conf.Load(path, &cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Port != 8443 {
+		t.Errorf("Port = %d, want 8443", cfg.Port)
+	}
+
+	broken := filepath.Join(dir, "broken.jsonnet")
+	if err := os.WriteFile(broken, []byte(`{ port: error "boom" }`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := conf.Load(broken, &cfg)
+	if err == nil || !strings.Contains(err.Error(), broken) {
+		t.Errorf("Load error = %v, want it to name %s", err, broken)
+	}
+}
@@ -0,0 +1,279 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nuln/conf/internal/fields"
+)
+
+// Strategy selects how Merge combines a value from a later layer with the
+// value already at the same path.
+type Strategy string
+
+// Merge strategies. Maps are always merged key by key; strategies decide
+// how lists are combined and how nulls are treated.
+const (
+	// Replace makes the later value win. It is the default.
+	Replace Strategy = "replace"
+
+	// Append adds the elements of a later list after the existing ones.
+	Append Strategy = "append"
+
+	// Prepend adds the elements of a later list before the existing ones.
+	Prepend Strategy = "prepend"
+
+	// DeleteNull makes a null in a later layer remove the key instead of
+	// setting it to null. It applies to the path and everything below it,
+	// so WithStrategy("", DeleteNull) enables it for the whole document.
+	DeleteNull Strategy = "delete"
+)
+
+// UnionBy returns a strategy that merges list elements sharing the value
+// of the first of keys they define, and appends the others. Without keys,
+// elements are matched by "id" or "name". In a merge struct tag it is
+// written "union" or "union=key1,key2".
+func UnionBy(keys ...string) Strategy {
+	if len(keys) == 0 {
+		return "union"
+	}
+	return Strategy("union=" + strings.Join(keys, ","))
+}
+
+// unionKeys returns the keys of a UnionBy strategy, reporting false for
+// other strategies.
+func (s Strategy) unionKeys() ([]string, bool) {
+	switch {
+	case s == "union":
+		return []string{"id", "name"}, true
+	case strings.HasPrefix(string(s), "union="):
+		return strings.Split(string(s[len("union="):]), ","), true
+	}
+	return nil, false
+}
+
+func (s Strategy) valid() bool {
+	switch s {
+	case Replace, Append, Prepend, DeleteNull:
+		return true
+	}
+	keys, ok := s.unionKeys()
+	for _, key := range keys {
+		ok = ok && key != ""
+	}
+	return ok
+}
+
+// checkStrategies reports the first strategy that is not valid.
+func (o *options) checkStrategies() error {
+	for path, s := range o.strategies {
+		if !s.valid() {
+			return fmt.Errorf("conf: unknown merge strategy %q for path %q", string(s), path)
+		}
+	}
+	return nil
+}
+
+// WithStrategy sets the merge strategy for the value at path, in the
+// syntax of Lookup without list indices: "servers" or "servers.ports"
+// applies to every element of the servers list. It takes precedence over
+// merge struct tags.
+func WithStrategy(path string, s Strategy) Option {
+	return func(o *options) {
+		if o.strategies == nil {
+			o.strategies = make(map[string]Strategy)
+		}
+		o.strategies[path] = s
+	}
+}
+
+// Merge merges the layer src into dst in place, as configured by
+// WithStrategy options. Maps are merged recursively; for other values the
+// strategy at their path applies, which is Replace unless set otherwise.
+// src is not modified and shares no nodes with dst afterwards.
+func Merge(dst, src *Node, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.checkStrategies(); err != nil {
+		return err
+	}
+	*dst = *o.merge(nil, dst, src)
+	return nil
+}
+
+// merge returns the result of merging src into dst, which may be nil, at
+// the key path keys.
+func (o *options) merge(keys []string, dst, src *Node) *Node {
+	s := o.strategies[strings.Join(keys, ".")]
+	switch {
+	case dst == nil:
+		return o.prune(keys, src.Clone())
+	case dst.Kind == MapNode && src.Kind == MapNode:
+		for _, f := range src.Fields {
+			at := append(keys[:len(keys):len(keys)], f.Key)
+			if f.Value.Kind == NullNode && o.deletesNulls(at) {
+				dst.remove(f.Key)
+				continue
+			}
+			dst.set(f.Key, o.merge(at, dst.Get(f.Key), f.Value))
+		}
+		return dst
+	case dst.Kind == ListNode && src.Kind == ListNode:
+		switch s {
+		case Append:
+			dst.Items = append(dst.Items, src.Clone().Items...)
+			return dst
+		case Prepend:
+			dst.Items = append(src.Clone().Items, dst.Items...)
+			return dst
+		}
+		if unionKeys, ok := s.unionKeys(); ok {
+			for _, item := range src.Items {
+				if i := matchItem(dst.Items, item, unionKeys); i >= 0 {
+					dst.Items[i] = o.merge(keys, dst.Items[i], item)
+				} else {
+					dst.Items = append(dst.Items, o.merge(keys, nil, item))
+				}
+			}
+			return dst
+		}
+	}
+	return o.prune(keys, src.Clone())
+}
+
+// prune removes the nulls from n that DeleteNull strategies apply to.
+func (o *options) prune(keys []string, n *Node) *Node {
+	if n.Kind != MapNode {
+		return n
+	}
+	fields := n.Fields[:0]
+	for _, f := range n.Fields {
+		at := append(keys[:len(keys):len(keys)], f.Key)
+		if f.Value.Kind == NullNode && o.deletesNulls(at) {
+			continue
+		}
+		fields = append(fields, Field{Key: f.Key, Value: o.prune(at, f.Value)})
+	}
+	n.Fields = fields
+	return n
+}
+
+// deletesNulls reports whether a DeleteNull strategy applies at keys.
+func (o *options) deletesNulls(keys []string) bool {
+	for i := len(keys); i >= 0; i-- {
+		if o.strategies[strings.Join(keys[:i], ".")] == DeleteNull {
+			return true
+		}
+	}
+	return false
+}
+
+// matchItem returns the index of the map in items that shares the value of
+// the first of keys defined by item, or -1.
+func matchItem(items []*Node, item *Node, keys []string) int {
+	for _, key := range keys {
+		id := item.Get(key)
+		if id == nil {
+			continue
+		}
+		for i, candidate := range items {
+			if v := candidate.Get(key); v != nil && equal(v, id) {
+				return i
+			}
+		}
+		return -1
+	}
+	return -1
+}
+
+// LoadLayers loads the files at paths in order, merges each into the ones
+// before it and decodes the result into v. Merge strategies are taken from
+// merge struct tags on the type of v and from WithStrategy options:
+//
+//	type Config struct {
+//	    Tags    []string `yaml:"tags"    merge:"append"`
+//	    Servers []Server `yaml:"servers" merge:"union=name"`
+//	}
+//
+//	err := conf.LoadLayers([]string{"base.yaml", "prod.toml"}, &cfg)
+//
+// The files may be in different formats; the merged document is decoded
+// with the codec of the first file. WithMetadata reports which file
+// supplied each value.
+func LoadLayers(paths []string, v any, opts ...Option) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("conf: no files to load")
+	}
+	o := newOptions(append([]Option{withTagStrategies(v)}, opts...))
+	if err := o.checkStrategies(); err != nil {
+		return err
+	}
+
+	var (
+		first  Codec
+		merged *Node
+	)
+	for _, path := range paths {
+		codec, data, err := readFile(path, o)
+		if err != nil {
+			return err
+		}
+		n, err := decodeNode(codec, data)
+		if err != nil {
+			return fmt.Errorf("conf: decoding %s: %w", path, err)
+		}
+		if first == nil {
+			first = codec
+		}
+		setOrigin(n, &Source{Kind: FileSource, Name: path})
+		merged = o.merge(nil, merged, n)
+	}
+
+	if err := decodeTree(first, merged, v, o); err != nil {
+		return fmt.Errorf("conf: decoding %s: %w", strings.Join(paths, " + "), err)
+	}
+	return nil
+}
+
+// decodeTree decodes n into v. Unless v uses conf struct tags or o requires
+// decoding by conf, n is encoded with codec and the result decoded, so that
+// v is populated with the codec's usual rules. The metadata requested by
+// WithMetadata is taken from the origins of the nodes of n, under the names
+// of the fields they are bound to when conf decodes v itself.
+func decodeTree(codec Codec, n *Node, v any, o *options) error {
+	var md Metadata
+	if o.metadata != nil {
+		md = Metadata{}
+		*o.metadata = md
+	}
+	if o.binds(v) {
+		b := newBinder(tagFor(codec), o)
+		b.sources = md
+		return bindValue(n, v, b)
+	}
+	if md != nil {
+		collectSources(md, nil, n)
+	}
+	if target, ok := v.(*Node); ok {
+		*target = *n
+		return nil
+	}
+	data, err := encodeNode(codec, n)
+	if err != nil {
+		return err
+	}
+	return codec.Decode(data, v)
+}
+
+// withTagStrategies returns an option setting the strategies declared by
+// merge struct tags on the type of v, under every name of the tagged
+// fields.
+func withTagStrategies(v any) Option {
+	return func(o *options) {
+		fields.Walk(reflect.TypeOf(v), "json", true, func(f fields.Field) {
+			if s, ok := f.Tag.Lookup("merge"); ok {
+				WithStrategy(strings.Join(f.Keys, "."), Strategy(s))(o)
+			}
+		})
+	}
+}
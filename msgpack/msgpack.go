@@ -0,0 +1,53 @@
+// Package msgpack provides a MessagePack codec for the conf package.
+// Import this package to register the "msgpack" codec:
+//
+//	import _ "github.com/nuln/conf/msgpack"
+//
+// Struct fields are named by their msgpack tag, falling back to the json
+// tag, so structs written for the text codecs can be used unchanged.
+package msgpack
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.Register("msgpack", New())
+}
+
+// New returns a new MessagePack codec.
+func New() conf.Codec {
+	return &msgpackCodec{}
+}
+
+type msgpackCodec struct{}
+
+func (c *msgpackCodec) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	encoder.SetSortMapKeys(true)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *msgpackCodec) Decode(data []byte, v any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	decoder.SetMapDecoder(func(d *msgpack.Decoder) (any, error) {
+		return d.DecodeUntypedMap()
+	})
+	return decoder.Decode(v)
+}
+
+func (c *msgpackCodec) Extensions() []string {
+	return []string{".msgpack"}
+}
+
+var _ conf.Codec = (*msgpackCodec)(nil)
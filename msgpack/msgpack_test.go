@@ -0,0 +1,44 @@
+package msgpack_test
+
+import (
+	"testing"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cm "github.com/nuln/conf/msgpack"
+)
+
+func TestMsgpack(t *testing.T) {
+	conftest.Suite(t, cm.New())
+}
+
+func TestMsgpackRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "msgpack" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("msgpack should be registered via init()")
+	}
+}
+
+func TestMsgpackBytesRoundTrip(t *testing.T) {
+	original := map[string]any{"name": "edge", "port": 8080}
+
+	data, err := conf.SaveToBytes(original, "msgpack")
+	if err != nil {
+		t.Fatalf("SaveToBytes failed: %v", err)
+	}
+
+	var loaded map[string]any
+	if err := conf.LoadFromBytes(data, "msgpack", &loaded); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if loaded["name"] != "edge" {
+		t.Errorf("name: got %#v", loaded["name"])
+	}
+}
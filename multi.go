@@ -0,0 +1,92 @@
+package conf
+
+import "fmt"
+
+// MultiDecoder is an optional interface implemented by codecs whose files
+// may hold a sequence of documents, such as multi-document YAML streams or
+// JSON Lines.
+type MultiDecoder interface {
+	// DecodeAll calls fn for each document in data, in order, passing a
+	// function that decodes that document into the value pointed to by v.
+	// It stops at the first error returned by fn.
+	DecodeAll(data []byte, fn func(decode func(v any) error) error) error
+}
+
+// LoadAll reads the file at path and calls fn for each document it
+// contains, with the zero-based document index and a function that decodes
+// the document into v. The format is detected from the file extension.
+// As with Load, the format falls back to content detection and may be
+// forced with WithFormat.
+// Codecs that do not implement MultiDecoder yield a single document.
+// Errors returned by fn are returned unchanged.
+func LoadAll(path string, fn func(i int, decode func(v any) error) error, opts ...Option) error {
+	o := newOptions(opts)
+	codec, data, err := readFile(path, o)
+	if err != nil {
+		return err
+	}
+
+	return decodeAll(codec, data, path, fn, o)
+}
+
+// LoadAllFromBytes calls fn for each document in data, which is in the
+// named format. See LoadAll.
+func LoadAllFromBytes(data []byte, format string, fn func(i int, decode func(v any) error) error, opts ...Option) error {
+	codec := Get(format)
+	if codec == nil {
+		return fmt.Errorf("%w: %q (available: %v)", ErrUnsupportedFormat, format, Available())
+	}
+	return decodeAll(codec, data, format, fn, newOptions(opts))
+}
+
+func decodeAll(codec Codec, data []byte, name string, fn func(i int, decode func(v any) error) error, o *options) error {
+	md, ok := codec.(MultiDecoder)
+	if !ok {
+		return fn(0, func(v any) error {
+			if err := decode(codec, data, v, o); err != nil {
+				return fmt.Errorf("conf: decoding %s: %w", name, err)
+			}
+			return nil
+		})
+	}
+
+	var (
+		i     int
+		fnErr error
+	)
+	err := md.DecodeAll(data, func(decode func(v any) error) error {
+		idx := i
+		fnErr = fn(idx, func(v any) error {
+			if err := decodeDocument(decode, v, newBinder(tagFor(codec), o), o); err != nil {
+				return fmt.Errorf("conf: decoding %s document %d: %w", name, idx, err)
+			}
+			return nil
+		})
+		i++
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("conf: decoding %s document %d: %w", name, i, err)
+	}
+	return nil
+}
+
+// decodeDocument decodes a document into v with the decode function of a
+// MultiDecoder, going through a tree with b if o requires it for v.
+func decodeDocument(decode func(v any) error, v any, b *binder, o *options) error {
+	if !o.binds(v) {
+		return decode(v)
+	}
+	var raw any
+	if err := decode(&raw); err != nil {
+		return err
+	}
+	n, err := NewNode(raw)
+	if err != nil {
+		return err
+	}
+	return bindValue(n, v, b)
+}
@@ -0,0 +1,442 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuln/conf/internal/scalar"
+	"github.com/nuln/conf/internal/textpos"
+)
+
+// Kind identifies the type of a Node.
+type Kind uint8
+
+// Node kinds.
+const (
+	NullNode Kind = iota
+	BoolNode
+	IntNode
+	FloatNode
+	StringNode
+	TimeNode
+	BytesNode
+	MapNode
+	ListNode
+)
+
+var kindNames = [...]string{
+	NullNode:   "null",
+	BoolNode:   "bool",
+	IntNode:    "int",
+	FloatNode:  "float",
+	StringNode: "string",
+	TimeNode:   "time",
+	BytesNode:  "bytes",
+	MapNode:    "map",
+	ListNode:   "list",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Node is a format-neutral document tree. Unlike map[string]any, it keeps
+// the order of map keys and distinguishes integers from floats regardless
+// of the library that produced it.
+type Node struct {
+	Kind Kind
+
+	// Value holds the value of a scalar node: nil, bool, int64, float64,
+	// string, time.Time or []byte, according to Kind.
+	Value any
+
+	// Fields holds the entries of a MapNode in document order.
+	Fields []Field
+
+	// Items holds the elements of a ListNode.
+	Items []*Node
+
+	// Line and Column give the 1-based position of the value in the
+	// source document. They are zero when the codec does not report
+	// positions or the node was not decoded from a document.
+	Line, Column int
+
+	// origin is the source of the node when it was loaded by LoadLayers.
+	origin *Source
+}
+
+// Field is an entry of a MapNode.
+type Field struct {
+	Key   string
+	Value *Node
+}
+
+// NodeCodec is an optional interface implemented by codecs that can decode
+// into and encode from a Node directly, preserving key order and scalar
+// types. Codecs without it go through map[string]any.
+type NodeCodec interface {
+	DecodeNode(data []byte) (*Node, error)
+	EncodeNode(n *Node) ([]byte, error)
+}
+
+// NewNode converts a Go value to a Node. Maps are ordered by key; structs
+// using conf tags are converted as by Save, and other types through their
+// JSON encoding.
+func NewNode(v any) (*Node, error) {
+	switch v := v.(type) {
+	case nil:
+		return &Node{Kind: NullNode}, nil
+	case *Node:
+		return v, nil
+	case bool:
+		return &Node{Kind: BoolNode, Value: v}, nil
+	case string:
+		return &Node{Kind: StringNode, Value: v}, nil
+	case time.Time:
+		return &Node{Kind: TimeNode, Value: v}, nil
+	case []byte:
+		return &Node{Kind: BytesNode, Value: v}, nil
+	case json.Number:
+		return numberNode(v.String())
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Node{Kind: IntNode, Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return &Node{Kind: IntNode, Value: int64(u)}, nil
+		}
+		return &Node{Kind: FloatNode, Value: float64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Node{Kind: FloatNode, Value: rv.Float()}, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return &Node{Kind: NullNode}, nil
+		}
+		if rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Struct {
+			break
+		}
+		return NewNode(rv.Elem().Interface())
+	case reflect.Map:
+		if rv.IsNil() {
+			return &Node{Kind: NullNode}, nil
+		}
+		keys := make([]string, 0, rv.Len())
+		values := make(map[string]reflect.Value, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, key)
+			values[key] = iter.Value()
+		}
+		sort.Strings(keys)
+		n := &Node{Kind: MapNode, Fields: make([]Field, len(keys))}
+		for i, key := range keys {
+			value, err := NewNode(values[key].Interface())
+			if err != nil {
+				return nil, err
+			}
+			n.Fields[i] = Field{Key: key, Value: value}
+		}
+		return n, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return &Node{Kind: NullNode}, nil
+		}
+		n := &Node{Kind: ListNode, Items: make([]*Node, rv.Len())}
+		for i := range n.Items {
+			item, err := NewNode(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			n.Items[i] = item
+		}
+		return n, nil
+	case reflect.String:
+		return &Node{Kind: StringNode, Value: rv.String()}, nil
+	case reflect.Bool:
+		return &Node{Kind: BoolNode, Value: rv.Bool()}, nil
+	}
+
+	if bindable(v) {
+		return unbindValue(v, "json")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("conf: converting %T to a node: %w", v, err)
+	}
+	n := &Node{}
+	if err := n.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Get returns the value of the field key of a MapNode, or nil.
+func (n *Node) Get(key string) *Node {
+	if n == nil || n.Kind != MapNode {
+		return nil
+	}
+	for i := len(n.Fields) - 1; i >= 0; i-- {
+		if n.Fields[i].Key == key {
+			return n.Fields[i].Value
+		}
+	}
+	return nil
+}
+
+// Interface converts n to plain Go values: map[string]any for maps, []any
+// for lists and the scalar Value otherwise.
+func (n *Node) Interface() any {
+	if n == nil {
+		return nil
+	}
+	switch n.Kind {
+	case MapNode:
+		m := make(map[string]any, len(n.Fields))
+		for _, f := range n.Fields {
+			m[f.Key] = f.Value.Interface()
+		}
+		return m
+	case ListNode:
+		s := make([]any, len(n.Items))
+		for i, item := range n.Items {
+			s[i] = item.Interface()
+		}
+		return s
+	}
+	return n.Value
+}
+
+// MarshalJSON encodes n as JSON, keeping the order of map keys. Floats
+// always carry a decimal point or exponent so that they decode as floats
+// again; times are written as RFC 3339 strings and bytes as base64.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := n.writeJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *Node) writeJSON(buf *bytes.Buffer) error {
+	if n == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	switch n.Kind {
+	case MapNode:
+		buf.WriteByte('{')
+		for i, f := range n.Fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, f.Key)
+			buf.WriteByte(':')
+			if err := f.Value.writeJSON(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case ListNode:
+		buf.WriteByte('[')
+		for i, item := range n.Items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := item.writeJSON(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case StringNode:
+		writeJSONString(buf, n.Value.(string))
+	case TimeNode:
+		writeJSONString(buf, scalar.FormatTime(n.Value.(time.Time)))
+	case FloatNode:
+		f := n.Value.(float64)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("conf: %v cannot be represented in JSON", f)
+		}
+		buf.WriteString(scalar.FormatFloat(f))
+	default:
+		data, err := json.Marshal(n.Value)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string without escaping HTML, which is
+// left to the encoder that embeds the result.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline.
+}
+
+// UnmarshalJSON decodes JSON into n, keeping the order of object keys and
+// recording the position of each value in data. Numbers without a
+// fraction or exponent become IntNode if they fit in an int64, and
+// FloatNode otherwise.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	d := &jsonDecoder{Decoder: decoder, data: data, index: textpos.NewIndex(data)}
+	node, err := d.value()
+	if err != nil {
+		return err
+	}
+	*n = *node
+	return nil
+}
+
+type jsonDecoder struct {
+	*json.Decoder
+	data  []byte
+	index textpos.Index
+}
+
+func (d *jsonDecoder) value() (*Node, error) {
+	// The next value starts after the separators that follow the
+	// previous token.
+	off := int(d.InputOffset())
+	for off < len(d.data) && strings.IndexByte(" \t\r\n,:", d.data[off]) >= 0 {
+		off++
+	}
+	line, column := d.index.Position(off)
+
+	n, err := d.token()
+	if err != nil {
+		return nil, err
+	}
+	n.Line, n.Column = line, column
+	return n, nil
+}
+
+func (d *jsonDecoder) token() (*Node, error) {
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			n := &Node{Kind: MapNode, Fields: []Field{}}
+			for d.More() {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := d.value()
+				if err != nil {
+					return nil, err
+				}
+				n.Fields = append(n.Fields, Field{Key: key.(string), Value: value})
+			}
+			_, err := d.Token()
+			return n, err
+		}
+		n := &Node{Kind: ListNode, Items: []*Node{}}
+		for d.More() {
+			item, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			n.Items = append(n.Items, item)
+		}
+		_, err := d.Token()
+		return n, err
+	case json.Number:
+		return numberNode(tok.String())
+	}
+	return NewNode(tok)
+}
+
+// numberNode parses a JSON number, keeping integers as integers.
+func numberNode(s string) (*Node, error) {
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &Node{Kind: IntNode, Value: i}, nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("conf: invalid number %q", s)
+	}
+	return &Node{Kind: FloatNode, Value: f}, nil
+}
+
+// decode decodes data into v using codec, building a tree if v is a *Node
+// and going through one if v uses conf struct tags or o requires it.
+func decode(codec Codec, data []byte, v any, o *options) error {
+	if n, ok := v.(*Node); ok {
+		node, err := decodeNode(codec, data)
+		if err != nil {
+			return err
+		}
+		*n = *node
+		return nil
+	}
+	if o.binds(v) {
+		n, err := decodeNode(codec, data)
+		if err != nil {
+			return err
+		}
+		return bindValue(n, v, newBinder(tagFor(codec), o))
+	}
+	return codec.Decode(data, v)
+}
+
+// encode encodes v using codec, writing a tree in order if v is a *Node
+// and going through one if v uses conf struct tags.
+func encode(codec Codec, v any) ([]byte, error) {
+	if n, ok := v.(*Node); ok {
+		return encodeNode(codec, n)
+	}
+	if bindable(v) {
+		n, err := unbindValue(v, tagFor(codec))
+		if err != nil {
+			return nil, err
+		}
+		return encodeNode(codec, n)
+	}
+	return codec.Encode(v)
+}
+
+// decodeNode decodes data into a Node using codec, falling back to
+// decoding into any for codecs that do not implement NodeCodec.
+func decodeNode(codec Codec, data []byte) (*Node, error) {
+	if nc, ok := codec.(NodeCodec); ok {
+		return nc.DecodeNode(data)
+	}
+	var v any
+	if err := codec.Decode(data, &v); err != nil {
+		return nil, err
+	}
+	return NewNode(v)
+}
+
+// encodeNode encodes n using codec, falling back to encoding its plain Go
+// values for codecs that do not implement NodeCodec.
+func encodeNode(codec Codec, n *Node) ([]byte, error) {
+	if nc, ok := codec.(NodeCodec); ok {
+		return nc.EncodeNode(n)
+	}
+	return codec.Encode(n.Interface())
+}
@@ -0,0 +1,48 @@
+package conf
+
+import "fmt"
+
+// Option configures the file functions Load, Save, LoadAll, Edit and
+// LoadLayers, as well as LoadFromBytes, LoadAllFromBytes and Merge.
+type Option func(*options)
+
+type options struct {
+	format     string
+	strategies map[string]Strategy // by path, see WithStrategy
+	metadata   *Metadata
+	keyMatch   KeyMatch
+
+	deprecation func(Deprecation) // see WithDeprecationHandler
+}
+
+// WithFormat forces the named codec (e.g. "yaml") regardless of the file
+// name, so that files such as /etc/myapp/config or settings.conf can be
+// read and written in a known format. Extension and content detection,
+// including compressor extensions, are skipped.
+func WithFormat(name string) Option {
+	return func(o *options) {
+		o.format = name
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{deprecation: logDeprecation}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// codecFor returns the codec to use for the file at path: the one forced
+// by WithFormat if set, otherwise the one matched by the file extension.
+func (o *options) codecFor(path string) (Codec, error) {
+	if o.format == "" {
+		return codecForPath(path)
+	}
+	codec := Get(o.format)
+	if codec == nil {
+		return nil, fmt.Errorf("%w: %q for file %q (available: %v)",
+			ErrUnsupportedFormat, o.format, path, Available())
+	}
+	return codec, nil
+}
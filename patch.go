@@ -0,0 +1,344 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Clone returns a deep copy of n.
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	if n.Fields != nil {
+		c.Fields = make([]Field, len(n.Fields))
+		for i, f := range n.Fields {
+			c.Fields[i] = Field{Key: f.Key, Value: f.Value.Clone()}
+		}
+	}
+	if n.Items != nil {
+		c.Items = make([]*Node, len(n.Items))
+		for i, item := range n.Items {
+			c.Items[i] = item.Clone()
+		}
+	}
+	if b, ok := n.Value.([]byte); ok {
+		c.Value = append([]byte(nil), b...)
+	}
+	return &c
+}
+
+// set sets the field key of a MapNode, replacing an existing value in
+// place or appending a new field.
+func (n *Node) set(key string, v *Node) {
+	for i := len(n.Fields) - 1; i >= 0; i-- {
+		if n.Fields[i].Key == key {
+			n.Fields[i].Value = v
+			return
+		}
+	}
+	n.Fields = append(n.Fields, Field{Key: key, Value: v})
+}
+
+// remove deletes the field key of a MapNode, reporting whether it existed.
+func (n *Node) remove(key string) bool {
+	for i, f := range n.Fields {
+		if f.Key == key {
+			n.Fields = append(n.Fields[:i], n.Fields[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyMergePatch applies a JSON merge patch (RFC 7386) to doc in place.
+// patch may be a *Node loaded from any format, or any value accepted by
+// NewNode. Maps in patch are merged recursively, null values delete keys,
+// and any other value replaces the target.
+//
+//	var doc, patch conf.Node
+//	conf.Load("config.toml", &doc)
+//	conf.Load("override.yaml", &patch)
+//	conf.ApplyMergePatch(&doc, &patch)
+//	conf.Save("config.toml", &doc)
+func ApplyMergePatch(doc *Node, patch any) error {
+	if doc == nil {
+		return fmt.Errorf("conf: cannot patch a nil document")
+	}
+	p, err := NewNode(patch)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		p = &Node{Kind: NullNode}
+	}
+	*doc = *mergePatch(doc, p)
+	return nil
+}
+
+func mergePatch(target, patch *Node) *Node {
+	if patch.Kind != MapNode {
+		return patch.Clone()
+	}
+	if target == nil || target.Kind != MapNode {
+		target = &Node{Kind: MapNode, Fields: []Field{}}
+	}
+	for _, f := range patch.Fields {
+		if f.Value == nil || f.Value.Kind == NullNode {
+			target.remove(f.Key)
+			continue
+		}
+		target.set(f.Key, mergePatch(target.Get(f.Key), f.Value))
+	}
+	return target
+}
+
+// errPatchTest is returned when a JSON Patch "test" operation fails.
+var errPatchTest = errors.New("test failed")
+
+// ApplyJSONPatch applies a JSON Patch (RFC 6902) to doc. patch is a list
+// of operations, as a *Node loaded from any format or a value accepted by
+// NewNode:
+//
+//	[
+//	  {"op": "replace", "path": "/database/port", "value": 5433},
+//	  {"op": "add", "path": "/database/hosts/-", "value": "db3.internal"}
+//	]
+//
+// Paths are JSON Pointers (RFC 6901). The operations are applied
+// atomically: if one fails, doc is left unchanged and the error identifies
+// the operation. Missing paths wrap ErrKeyNotFound.
+func ApplyJSONPatch(doc *Node, patch any) error {
+	if doc == nil {
+		return fmt.Errorf("conf: cannot patch a nil document")
+	}
+	p, err := NewNode(patch)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		p = &Node{Kind: NullNode}
+	}
+	if p.Kind != ListNode {
+		return fmt.Errorf("conf: json patch must be a list of operations, got %s", p.Kind)
+	}
+
+	result := doc.Clone()
+	for i, op := range p.Items {
+		if err := applyOperation(&result, op); err != nil {
+			name, _ := GetString(op, "op")
+			path, _ := GetString(op, "path")
+			return fmt.Errorf("conf: json patch operation %d (%s %s): %w", i, name, path, err)
+		}
+	}
+	*doc = *result
+	return nil
+}
+
+func applyOperation(doc **Node, op *Node) error {
+	if op.Kind != MapNode {
+		return fmt.Errorf("operation must be a map, got %s", op.Kind)
+	}
+	name, err := GetString(op, "op")
+	if err != nil {
+		return err
+	}
+	path, err := patchPointer(op, "path")
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "add", "replace", "test":
+		value := op.Get("value")
+		if value == nil {
+			return fmt.Errorf("%w: value", ErrKeyNotFound)
+		}
+		switch name {
+		case "add":
+			return pointerAdd(doc, path, value.Clone())
+		case "replace":
+			return pointerReplace(doc, path, value.Clone())
+		}
+		target, err := pointerGet(*doc, path)
+		if err != nil {
+			return err
+		}
+		if !equal(target, value) {
+			return errPatchTest
+		}
+		return nil
+	case "remove":
+		return pointerRemove(doc, path)
+	case "move", "copy":
+		from, err := patchPointer(op, "from")
+		if err != nil {
+			return err
+		}
+		value, err := pointerGet(*doc, from)
+		if err != nil {
+			return err
+		}
+		if name == "copy" {
+			return pointerAdd(doc, path, value.Clone())
+		}
+		if len(path) > len(from) && equalTokens(path[:len(from)], from) {
+			return errors.New("cannot move a value into itself")
+		}
+		if err := pointerRemove(doc, from); err != nil {
+			return err
+		}
+		return pointerAdd(doc, path, value)
+	}
+	return fmt.Errorf("unknown operation %q", name)
+}
+
+// patchPointer returns the JSON Pointer stored under key in op.
+func patchPointer(op *Node, key string) ([]string, error) {
+	s, err := GetString(op, key)
+	if err != nil {
+		return nil, err
+	}
+	return parsePointer(s)
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func equalTokens(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pointerGet returns the value at the pointer tokens within doc.
+func pointerGet(doc *Node, tokens []string) (*Node, error) {
+	n := doc
+	for i, t := range tokens {
+		var err error
+		if n, err = pointerChild(n, t, tokens[:i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// pointerChild returns the child of n named by the reference token t; at
+// is the pointer up to and including t, for errors.
+func pointerChild(n *Node, t string, at []string) (*Node, error) {
+	switch n.Kind {
+	case MapNode:
+		if c := n.Get(t); c != nil {
+			return c, nil
+		}
+	case ListNode:
+		if i, err := listIndex(t, len(n.Items)-1); err == nil {
+			return n.Items[i], nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: /%s: want map or list, got %s",
+			ErrTypeMismatch, strings.Join(at[:len(at)-1], "/"), n.Kind)
+	}
+	return nil, fmt.Errorf("%w: /%s", ErrKeyNotFound, strings.Join(at, "/"))
+}
+
+// listIndex parses t as an index into a list, which must not exceed limit.
+func listIndex(t string, limit int) (int, error) {
+	i, err := strconv.Atoi(t)
+	if err != nil || i < 0 || i > limit || t != strconv.Itoa(i) {
+		return 0, fmt.Errorf("invalid index %q", t)
+	}
+	return i, nil
+}
+
+// pointerAdd adds value at the pointer tokens within *doc, replacing an
+// existing map value or inserting into a list.
+func pointerAdd(doc **Node, tokens []string, value *Node) error {
+	if len(tokens) == 0 {
+		*doc = value
+		return nil
+	}
+	parent, err := pointerGet(*doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+	switch parent.Kind {
+	case MapNode:
+		parent.set(last, value)
+		return nil
+	case ListNode:
+		i := len(parent.Items)
+		if last != "-" {
+			if i, err = listIndex(last, len(parent.Items)); err != nil {
+				return fmt.Errorf("%w: /%s", ErrKeyNotFound, strings.Join(tokens, "/"))
+			}
+		}
+		parent.Items = append(parent.Items, nil)
+		copy(parent.Items[i+1:], parent.Items[i:])
+		parent.Items[i] = value
+		return nil
+	}
+	return fmt.Errorf("%w: /%s: want map or list, got %s",
+		ErrTypeMismatch, strings.Join(tokens[:len(tokens)-1], "/"), parent.Kind)
+}
+
+// pointerReplace replaces the value at the pointer tokens within *doc,
+// which must exist, keeping its place in the parent.
+func pointerReplace(doc **Node, tokens []string, value *Node) error {
+	target, err := pointerGet(*doc, tokens)
+	if err != nil {
+		return err
+	}
+	*target = *value
+	return nil
+}
+
+// pointerRemove removes the value at the pointer tokens within *doc, which
+// must exist.
+func pointerRemove(doc **Node, tokens []string) error {
+	if len(tokens) == 0 {
+		*doc = &Node{Kind: NullNode}
+		return nil
+	}
+	if _, err := pointerGet(*doc, tokens); err != nil {
+		return err
+	}
+	parent, _ := pointerGet(*doc, tokens[:len(tokens)-1])
+	last := tokens[len(tokens)-1]
+	if parent.Kind == MapNode {
+		parent.remove(last)
+		return nil
+	}
+	i, _ := listIndex(last, len(parent.Items)-1)
+	parent.Items = append(parent.Items[:i], parent.Items[i+1:]...)
+	return nil
+}
+
+// equal reports whether a and b hold the same data, as compared by Diff.
+func equal(a, b *Node) bool {
+	var changes []Change
+	diff(nil, a, b, &changes)
+	return len(changes) == 0
+}
@@ -0,0 +1,318 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pathElem is one step of a path: a map key or a list index.
+type pathElem struct {
+	key   string
+	index int // -1 for keys
+
+	// field is the name of the struct field that key was bound to, which
+	// differs from key for aliases and keys matched loosely.
+	field string
+}
+
+// parsePath parses a path such as `database.hosts[0].port`. Keys that
+// contain dots or brackets may be quoted: `labels["app.kubernetes.io/name"]`.
+func parsePath(path string) ([]pathElem, error) {
+	var elems []pathElem
+	for i := 0; i < len(path); {
+		c := path[i]
+		switch {
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("conf: invalid path %q: unclosed '['", path)
+			}
+			inner := path[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				// The quoted key may itself contain ']'.
+				s, rest, err := unquotePrefix(path[i+1:])
+				if err != nil || !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("conf: invalid path %q: malformed quoted key", path)
+				}
+				elems = append(elems, pathElem{key: s, index: -1})
+				i = len(path) - len(rest) + 1
+				break
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("conf: invalid path %q: bad index %q", path, inner)
+			}
+			elems = append(elems, pathElem{index: n})
+			i += end + 1
+		case c == '.' && i > 0:
+			i++
+			if i == len(path) || path[i] == '.' || path[i] == '[' {
+				return nil, fmt.Errorf("conf: invalid path %q: empty key", path)
+			}
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("conf: invalid path %q: empty key", path)
+			}
+			elems = append(elems, pathElem{key: path[i : i+end], index: -1})
+			i += end
+		}
+		if c == '[' && i < len(path) && path[i] != '.' && path[i] != '[' {
+			return nil, fmt.Errorf("conf: invalid path %q: missing '.' after ']'", path)
+		}
+	}
+	return elems, nil
+}
+
+// unquotePrefix unquotes the Go string literal at the start of s and
+// returns the rest of s.
+func unquotePrefix(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			return v, s[i+1:], err
+		}
+	}
+	return "", "", strconv.ErrSyntax
+}
+
+// formatPath formats elems in the syntax accepted by parsePath.
+func formatPath(elems []pathElem) string {
+	var b strings.Builder
+	for i, e := range elems {
+		switch {
+		case e.index >= 0:
+			fmt.Fprintf(&b, "[%d]", e.index)
+		case e.key == "" || strings.ContainsAny(e.key, `.[]"`):
+			b.WriteString("[" + strconv.Quote(e.key) + "]")
+		default:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(e.key)
+		}
+	}
+	return b.String()
+}
+
+// Lookup returns the node at path within doc. Paths separate map keys with
+// dots and index lists with brackets, as in "database.hosts[0].port"; keys
+// containing dots are quoted, as in `labels["app.kubernetes.io/name"]`.
+// An empty path returns doc itself.
+//
+// Lookup returns an error wrapping ErrKeyNotFound if a key or index does
+// not exist, and ErrTypeMismatch if a key is applied to a list or an index
+// to a map.
+func Lookup(doc *Node, path string) (*Node, error) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	n := doc
+	for i, e := range elems {
+		n, err = step(n, e, elems[:i+1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// step returns the child of n selected by e; at is the path up to and
+// including e, for errors.
+func step(n *Node, e pathElem, at []pathElem) (*Node, error) {
+	if n == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, formatPath(at))
+	}
+	if e.index >= 0 {
+		if n.Kind != ListNode {
+			return nil, fmt.Errorf("%w: %s: want list, got %s", ErrTypeMismatch, formatPath(at[:len(at)-1]), n.Kind)
+		}
+		if e.index >= len(n.Items) {
+			return nil, fmt.Errorf("%w: %s (list has %d items)", ErrKeyNotFound, formatPath(at), len(n.Items))
+		}
+		return n.Items[e.index], nil
+	}
+	if n.Kind != MapNode {
+		return nil, fmt.Errorf("%w: %s: want map, got %s", ErrTypeMismatch, formatPath(at[:len(at)-1]), n.Kind)
+	}
+	child := n.Get(e.key)
+	if child == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, formatPath(at))
+	}
+	return child, nil
+}
+
+// Assign sets the value at path within doc, which must be a map or a list,
+// using the path syntax of Lookup. value may be a *Node or any Go value
+// accepted by NewNode. Missing map keys are created along the way; an index
+// equal to the length of a list appends to it. A null parent becomes a map
+// or a list as the path requires.
+func Assign(doc *Node, path string, value any) error {
+	if doc == nil {
+		return fmt.Errorf("conf: cannot assign to a nil document")
+	}
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return fmt.Errorf("conf: cannot assign to the document root")
+	}
+	v, err := NewNode(value)
+	if err != nil {
+		return err
+	}
+
+	n := doc
+	for i, e := range elems {
+		last := i == len(elems)-1
+		at := elems[:i+1]
+		if n.Kind == NullNode {
+			kind := MapNode
+			if e.index >= 0 {
+				kind = ListNode
+			}
+			*n = Node{Kind: kind, Line: n.Line, Column: n.Column}
+		}
+
+		if e.index >= 0 && n.Kind == ListNode && e.index == len(n.Items) {
+			child := v
+			if !last {
+				child = &Node{Kind: NullNode}
+			}
+			n.Items = append(n.Items, child)
+			n = child
+			continue
+		}
+		if e.index < 0 && n.Kind == MapNode && n.Get(e.key) == nil {
+			child := v
+			if !last {
+				child = &Node{Kind: NullNode}
+			}
+			n.Fields = append(n.Fields, Field{Key: e.key, Value: child})
+			n = child
+			continue
+		}
+
+		child, err := step(n, e, at)
+		if err != nil {
+			return err
+		}
+		if last {
+			*child = *v
+			return nil
+		}
+		n = child
+	}
+	return nil
+}
+
+// GetString returns the string at path within doc. It returns an error
+// wrapping ErrTypeMismatch if the value is not a string.
+func GetString(doc *Node, path string) (string, error) {
+	n, err := Lookup(doc, path)
+	if err != nil {
+		return "", err
+	}
+	if n.Kind != StringNode {
+		return "", mismatch(path, n, "string")
+	}
+	return n.Value.(string), nil
+}
+
+// GetInt returns the integer at path within doc. Floats without a
+// fractional part and strings holding an integer, as produced by formats
+// such as XML, are accepted too.
+func GetInt(doc *Node, path string) (int64, error) {
+	n, err := Lookup(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	switch n.Kind {
+	case IntNode:
+		return n.Value.(int64), nil
+	case FloatNode:
+		if f := n.Value.(float64); f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return int64(f), nil
+		}
+	case StringNode:
+		if i, err := strconv.ParseInt(n.Value.(string), 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	return 0, mismatch(path, n, "int")
+}
+
+// GetFloat returns the number at path within doc. Integers and strings
+// holding a number are accepted too.
+func GetFloat(doc *Node, path string) (float64, error) {
+	n, err := Lookup(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	switch n.Kind {
+	case FloatNode:
+		return n.Value.(float64), nil
+	case IntNode:
+		return float64(n.Value.(int64)), nil
+	case StringNode:
+		if f, err := strconv.ParseFloat(n.Value.(string), 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, mismatch(path, n, "float")
+}
+
+// GetBool returns the boolean at path within doc. Strings accepted by
+// strconv.ParseBool are accepted too.
+func GetBool(doc *Node, path string) (bool, error) {
+	n, err := Lookup(doc, path)
+	if err != nil {
+		return false, err
+	}
+	switch n.Kind {
+	case BoolNode:
+		return n.Value.(bool), nil
+	case StringNode:
+		if b, err := strconv.ParseBool(n.Value.(string)); err == nil {
+			return b, nil
+		}
+	}
+	return false, mismatch(path, n, "bool")
+}
+
+// GetDuration returns the duration at path within doc. Strings are parsed
+// with time.ParseDuration (e.g. "30s", "1h30m"); integers are taken as
+// nanoseconds, which is how time.Duration values are encoded.
+func GetDuration(doc *Node, path string) (time.Duration, error) {
+	n, err := Lookup(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	switch n.Kind {
+	case StringNode:
+		if d, err := time.ParseDuration(n.Value.(string)); err == nil {
+			return d, nil
+		}
+	case IntNode:
+		return time.Duration(n.Value.(int64)), nil
+	}
+	return 0, mismatch(path, n, "duration")
+}
+
+func mismatch(path string, n *Node, want string) error {
+	if n.Kind == StringNode {
+		return fmt.Errorf("%w: %s: want %s, got string %q", ErrTypeMismatch, path, want, n.Value)
+	}
+	return fmt.Errorf("%w: %s: want %s, got %s", ErrTypeMismatch, path, want, n.Kind)
+}
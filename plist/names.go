@@ -0,0 +1,110 @@
+package plist
+
+import (
+	"reflect"
+	"strings"
+
+	"howett.net/plist"
+
+	"github.com/nuln/conf/internal/fields"
+)
+
+// howett.net/plist names struct fields by their plist tag or else their Go
+// name. To fall back to the json, yaml and toml tags like the other
+// codecs, values whose types have fields with such tags are encoded and
+// decoded through generic values whose keys are renamed.
+
+// field is a struct field as named by the plist package and by the codec.
+type field struct {
+	typ   reflect.Type
+	plist string // key written and read by the plist package
+	name  string // key in documents
+	omit  bool   // omitempty from the tag naming the field
+}
+
+// renamed reports whether values of t need their keys renamed.
+func renamed(t reflect.Type) bool {
+	return t != nil && (fields.Tagged(t, "json") || fields.Tagged(t, "yaml") || fields.Tagged(t, "toml"))
+}
+
+// structFields returns the fields of the struct type t with the names used
+// by the plist package and by documents. Fields excluded by a "-" name are
+// left out.
+func structFields(t reflect.Type) []field {
+	fs := fields.Own(t, "plist")
+	out := make([]field, 0, len(fs))
+	for _, f := range fs {
+		key := f.Name
+		if name, _, _ := strings.Cut(f.Tag.Get("plist"), ","); name != "" {
+			key = name
+		}
+		name := f.Keys[0]
+		if f.Implicit {
+			name = f.Name
+		}
+		out = append(out, field{typ: f.Type, plist: key, name: name, omit: f.Has("omitempty")})
+	}
+	return out
+}
+
+// rename renames the keys of the generic value v of type t, from those of
+// the plist package to those of documents when encoding and back when
+// decoding. Keys of structs that name no field are dropped.
+func rename(v any, t reflect.Type, encoding bool) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Map:
+			for k, item := range v {
+				v[k] = rename(item, t.Elem(), encoding)
+			}
+			return v
+		case reflect.Struct:
+			out := make(map[string]any, len(v))
+			for _, f := range structFields(t) {
+				from, to := f.name, f.plist
+				if encoding {
+					from, to = to, from
+				}
+				item, ok := v[from]
+				if !ok || encoding && f.omit && empty(item) {
+					continue
+				}
+				out[to] = rename(item, f.typ, encoding)
+			}
+			return out
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range v {
+				v[i] = rename(item, t.Elem(), encoding)
+			}
+		}
+	}
+	return v
+}
+
+// empty reports whether the generic value v is empty for omitempty.
+func empty(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	}
+	return !rv.IsValid() || rv.IsZero()
+}
+
+// generic encodes v with the plist package and decodes the result into a
+// generic value.
+func generic(v any) (any, error) {
+	data, err := plist.Marshal(v, plist.BinaryFormat)
+	if err != nil {
+		return nil, err
+	}
+	var g any
+	_, err = plist.Unmarshal(data, &g)
+	return g, err
+}
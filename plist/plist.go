@@ -0,0 +1,100 @@
+// Package plist provides an Apple property list codec for the conf
+// package. Import this package to register the "plist" codec:
+//
+//	import _ "github.com/nuln/conf/plist"
+//
+// Decoding accepts XML, binary and OpenStep property lists, detecting the
+// variant from the content. Encoding writes XML by default; use Binary to
+// produce binary property lists instead. Struct fields are named by their
+// plist tag, falling back to the json, yaml and toml tags and then to
+// their Go name.
+package plist
+
+import (
+	"bytes"
+	"reflect"
+
+	"howett.net/plist"
+
+	"github.com/nuln/conf"
+)
+
+func init() {
+	conf.Register("plist", New())
+}
+
+// Option configures a plist codec created by New.
+type Option func(*plistCodec)
+
+// Binary makes Encode write binary property lists.
+func Binary() Option {
+	return func(c *plistCodec) {
+		c.format = plist.BinaryFormat
+	}
+}
+
+// New returns a new plist codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &plistCodec{format: plist.XMLFormat}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type plistCodec struct {
+	format int
+}
+
+func (c *plistCodec) Encode(v any) ([]byte, error) {
+	if t := reflect.TypeOf(v); renamed(t) {
+		g, err := generic(v)
+		if err != nil {
+			return nil, err
+		}
+		v = rename(g, t, true)
+	}
+	var buf bytes.Buffer
+	encoder := plist.NewEncoderForFormat(&buf, c.format)
+	if c.format == plist.XMLFormat {
+		encoder.Indent("\t")
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *plistCodec) Decode(data []byte, v any) error {
+	if t := reflect.TypeOf(v); renamed(t) {
+		var g any
+		if _, err := plist.Unmarshal(data, &g); err != nil {
+			return err
+		}
+		var err error
+		if data, err = plist.Marshal(rename(g, t, false), plist.BinaryFormat); err != nil {
+			return err
+		}
+	}
+	_, err := plist.Unmarshal(data, v)
+	return err
+}
+
+// Sniff reports whether data is a binary property list or an XML
+// property list.
+func (c *plistCodec) Sniff(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return true
+	}
+	head := data[:min(len(data), 512)]
+	return bytes.Contains(head, []byte("<!DOCTYPE plist")) || bytes.Contains(head, []byte("<plist"))
+}
+
+func (c *plistCodec) Extensions() []string {
+	return []string{".plist"}
+}
+
+var (
+	_ conf.Codec   = (*plistCodec)(nil)
+	_ conf.Sniffer = (*plistCodec)(nil)
+)
@@ -0,0 +1,117 @@
+package plist_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cp "github.com/nuln/conf/plist"
+)
+
+func TestPlist(t *testing.T) {
+	conftest.Suite(t, cp.New())
+}
+
+func TestPlistBinary(t *testing.T) {
+	conftest.Suite(t, cp.New(cp.Binary()))
+
+	data, err := cp.New(cp.Binary()).Encode(map[string]string{"theme": "dark"})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("bplist00")) {
+		t.Errorf("expected binary plist header, got %q", data[:8])
+	}
+}
+
+func TestPlistRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "plist" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("plist should be registered via init()")
+	}
+}
+
+func TestPlistJSONTagFallback(t *testing.T) {
+	type prefs struct {
+		Theme    string            `json:"theme"`
+		FontSize int               `plist:"font_size" json:"fontSize"`
+		Recent   []string          `json:"recent,omitempty"`
+		Secret   string            `json:"-"`
+		Windows  map[string]window `json:"windows"`
+		Untagged bool
+	}
+	original := prefs{
+		Theme:    "dark",
+		FontSize: 12,
+		Secret:   "hidden",
+		Windows:  map[string]window{"main": {Width: 800}},
+		Untagged: true,
+	}
+
+	data, err := cp.New().Encode(original)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	s := string(data)
+	for _, want := range []string{"<key>theme</key>", "<key>font_size</key>", "<key>width</key>", "<key>Untagged</key>"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output does not contain %s:\n%s", want, s)
+		}
+	}
+	for _, unwanted := range []string{"Theme", "recent", "Secret", "hidden"} {
+		if strings.Contains(s, unwanted) {
+			t.Errorf("output contains %s:\n%s", unwanted, s)
+		}
+	}
+
+	var decoded prefs
+	if err := cp.New().Decode(data, &decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	original.Secret = ""
+	if decoded.Theme != "dark" || decoded.FontSize != 12 || decoded.Windows["main"].Width != 800 || !decoded.Untagged || decoded.Secret != "" {
+		t.Errorf("got %+v, want %+v", decoded, original)
+	}
+}
+
+func TestPlistYAMLAndTOMLTagFallback(t *testing.T) {
+	type prefs struct {
+		Theme  string `yaml:"theme"`
+		Scale  int    `toml:"scale"`
+		Hidden string `yaml:"-"`
+	}
+	data, err := cp.New().Encode(prefs{Theme: "dark", Scale: 2, Hidden: "x"})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	s := string(data)
+	for _, want := range []string{"<key>theme</key>", "<key>scale</key>"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output does not contain %s:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "Hidden") {
+		t.Errorf("output contains Hidden:\n%s", s)
+	}
+
+	var decoded prefs
+	if err := cp.New().Decode(data, &decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Theme != "dark" || decoded.Scale != 2 {
+		t.Errorf("got %+v", decoded)
+	}
+}
+
+type window struct {
+	Width int `json:"width"`
+}
@@ -0,0 +1,49 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Sniffer is an optional interface implemented by codecs that can
+// recognize their format from content. Load uses it when a file has no
+// extension or one without a registered codec, as is common under /etc.
+type Sniffer interface {
+	// Sniff reports whether data appears to be in the codec's format.
+	Sniff(data []byte) bool
+}
+
+// DetectFormat returns the name of the first registered codec, in name
+// order, whose Sniff method accepts data. It returns "" if none does.
+func DetectFormat(data []byte) string {
+	for _, name := range Available() {
+		if s, ok := Get(name).(Sniffer); ok && s.Sniff(data) {
+			return name
+		}
+	}
+	return ""
+}
+
+// readFile reads the file at path and returns it along with its codec,
+// which is forced by o, or detected from the extension or, failing that,
+// from the content, and bound to path if it is a FileCodec.
+func readFile(path string, o *options) (Codec, []byte, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is user-provided by design
+	if err != nil {
+		return nil, nil, fmt.Errorf("conf: reading %s: %w", path, err)
+	}
+
+	codec, err := o.codecFor(path)
+	if err != nil {
+		if o.format != "" || !errors.Is(err, ErrUnsupportedFormat) {
+			return nil, nil, err
+		}
+		name := DetectFormat(data)
+		if name == "" {
+			return nil, nil, fmt.Errorf("%w (content not recognized)", err)
+		}
+		codec = Get(name)
+	}
+	return forFile(codec, path), data, nil
+}
@@ -0,0 +1,158 @@
+package conf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SourceKind is the kind of a Source.
+type SourceKind uint8
+
+// Source kinds.
+const (
+	FileSource SourceKind = iota + 1
+	FlagSource
+)
+
+func (k SourceKind) String() string {
+	switch k {
+	case FileSource:
+		return "file"
+	case FlagSource:
+		return "flag"
+	}
+	return fmt.Sprintf("SourceKind(%d)", uint8(k))
+}
+
+// Source describes where a configuration value came from.
+type Source struct {
+	Kind SourceKind
+
+	// Name is the file path or flag name. It is empty for documents given
+	// to LoadFromBytes.
+	Name string
+
+	// Line and Column give the position of the value within a file, when
+	// the codec reports positions.
+	Line, Column int
+}
+
+// String formats s as "prod.toml:4:8" or "flag --port". Documents given
+// to LoadFromBytes are named "<input>".
+func (s Source) String() string {
+	switch s.Kind {
+	case FileSource:
+		name := s.Name
+		if name == "" {
+			name = "<input>"
+		}
+		if s.Line == 0 {
+			return name
+		}
+		return fmt.Sprintf("%s:%d:%d", name, s.Line, s.Column)
+	case FlagSource:
+		return "flag --" + s.Name
+	}
+	return s.Kind.String()
+}
+
+// Metadata maps the path of each leaf value of a loaded configuration, in
+// the syntax of Lookup, to the source that supplied its final value. Where
+// conf decodes a struct itself, as for conf tags or WithKeyMatch, paths
+// name its fields as the struct does, whatever alias or spelling the
+// document used.
+type Metadata map[string]Source
+
+// String lists the sources of all values, one "path: source" line per
+// value in path order, for logs and debugging output.
+func (m Metadata) String() string {
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s: %s\n", path, m[path])
+	}
+	return b.String()
+}
+
+// Source returns the source of the value at path, which may take any form
+// accepted by Lookup. It reports false unless path names a loaded value
+// that is not a non-empty map or list.
+func (m Metadata) Source(path string) (Source, bool) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return Source{}, false
+	}
+	src, ok := m[formatPath(elems)]
+	return src, ok
+}
+
+// WithMetadata makes Load, LoadFromBytes and LoadLayers record the source
+// of each value in md, answering questions such as why a value differs
+// between environments:
+//
+//	var md conf.Metadata
+//	err := conf.LoadLayers(paths, &cfg, conf.WithMetadata(&md))
+//	src, _ := md.Source("database.port")
+//	fmt.Println(src) // prod.toml:4:8
+func WithMetadata(md *Metadata) Option {
+	return func(o *options) {
+		o.metadata = md
+	}
+}
+
+// decodeFile decodes data, read from the file name, into v using codec,
+// recording the source of each value if WithMetadata asks for them. The
+// document is decoded only once either way.
+func decodeFile(codec Codec, data []byte, name string, v any, o *options) error {
+	if o.metadata == nil {
+		return decode(codec, data, v, o)
+	}
+	n, err := decodeNode(codec, data)
+	if err != nil {
+		return err
+	}
+	setOrigin(n, &Source{Kind: FileSource, Name: name})
+	return decodeTree(codec, n, v, o)
+}
+
+// setOrigin records src as the origin of n and all nodes below it.
+func setOrigin(n *Node, src *Source) {
+	n.origin = src
+	for _, f := range n.Fields {
+		setOrigin(f.Value, src)
+	}
+	for _, item := range n.Items {
+		setOrigin(item, src)
+	}
+}
+
+// hasChildren reports whether n is a non-empty map or list.
+func hasChildren(n *Node) bool {
+	return n.Kind == MapNode && len(n.Fields) > 0 || n.Kind == ListNode && len(n.Items) > 0
+}
+
+// collectSources records the origin of each leaf below n in md. Empty maps
+// and lists count as leaves.
+func collectSources(md Metadata, path []pathElem, n *Node) {
+	switch {
+	case n.Kind == MapNode && len(n.Fields) > 0:
+		for _, f := range n.Fields {
+			collectSources(md, append(path[:len(path):len(path)], pathElem{key: f.Key, index: -1}), f.Value)
+		}
+	case n.Kind == ListNode && len(n.Items) > 0:
+		for i, item := range n.Items {
+			collectSources(md, append(path[:len(path):len(path)], pathElem{index: i}), item)
+		}
+	case n.origin != nil && len(path) > 0:
+		src := *n.origin
+		if src.Kind == FileSource {
+			src.Line, src.Column = n.Line, n.Column
+		}
+		md[formatPath(path)] = src
+	}
+}
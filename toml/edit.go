@@ -0,0 +1,599 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/nuln/conf"
+)
+
+// Edit parses data into a document that can be modified in place.
+// Changed values are rewritten where they stand; all other text, including
+// comments and blank lines, is preserved byte for byte.
+func (c *tomlCodec) Edit(data []byte) (conf.EditableDocument, error) {
+	d := &document{indent: c.indent}
+	if err := d.reset(data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+var _ conf.Editor = (*tomlCodec)(nil)
+
+type document struct {
+	src    []byte
+	tables []*table // tables[0] is the root table
+	indent string
+}
+
+// table is a [table] or [[array]] section and the key/value entries
+// directly under it.
+type table struct {
+	path    []string
+	array   bool
+	start   int // start of the header line
+	body    int // end of the header line
+	entries []*entry
+}
+
+// addressable returns the entries of t that can be reached by path, which
+// excludes those of array tables.
+func (t *table) addressable() []*entry {
+	if t.array {
+		return nil
+	}
+	return t.entries
+}
+
+// entry is a key/value line. path is the full path from the document root.
+type entry struct {
+	path                 []string
+	start, end           int // the whole line, including its newline
+	valueStart, valueEnd int
+}
+
+func (d *document) Bytes() []byte {
+	return d.src
+}
+
+func (d *document) Get(path []string, v any) error {
+	if len(path) == 0 {
+		_, err := toml.Decode(string(d.src), v)
+		return err
+	}
+	var top map[string]toml.Primitive
+	md, err := toml.Decode(string(d.src), &top)
+	if err != nil {
+		return err
+	}
+
+	level := top
+	for i, key := range path {
+		prim, ok := level[key]
+		if !ok {
+			return fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+		}
+		if i == len(path)-1 {
+			return md.PrimitiveDecode(prim, v)
+		}
+		level = nil
+		if err := md.PrimitiveDecode(prim, &level); err != nil {
+			return fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+		}
+	}
+	return nil
+}
+
+func (d *document) Set(path []string, value any) error {
+	if len(path) == 0 {
+		return fmt.Errorf("toml: cannot replace the document root")
+	}
+	val, err := normalize(value)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range d.tables {
+		for _, e := range t.addressable() {
+			switch {
+			case equalPath(e.path, path):
+				return d.splice(e.valueStart, e.valueEnd, formatInline(val))
+			case hasPrefix(path, e.path):
+				return d.setInline(e, path[len(e.path):], val)
+			}
+		}
+	}
+
+	if d.covers(path) {
+		// Replace the existing table or dotted keys as a whole.
+		if err := d.Delete(path); err != nil {
+			return err
+		}
+		return d.Set(path, value)
+	}
+	if _, isTable := val.(map[string]any); isTable {
+		return d.appendTable(path, val)
+	}
+
+	parent := d.tables[0]
+	for _, t := range d.tables[1:] {
+		if !t.array && hasPrefix(path, t.path) && len(t.path) > len(parent.path) {
+			parent = t
+		}
+	}
+	line := formatKey(path[len(parent.path):]) + " = " + formatInline(val) + "\n"
+	pos := parent.body
+	if len(parent.entries) > 0 {
+		pos = parent.entries[len(parent.entries)-1].end
+	}
+	src := d.src
+	if pos > 0 && src[pos-1] != '\n' {
+		line = "\n" + line
+	}
+	return d.reset(insertAt(src, pos, line))
+}
+
+func (d *document) Delete(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("toml: cannot delete the document root")
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for i, t := range d.tables {
+		if i > 0 && hasPrefix(t.path, path) {
+			end := len(d.src)
+			if i+1 < len(d.tables) {
+				end = d.tables[i+1].start
+			}
+			spans = append(spans, span{d.commentsAbove(t.start), end})
+			continue
+		}
+		for _, e := range t.addressable() {
+			if hasPrefix(e.path, path) {
+				spans = append(spans, span{d.commentsAbove(e.start), e.end})
+			} else if hasPrefix(path, e.path) {
+				return d.deleteInline(e, path[len(e.path):])
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+	}
+
+	src := d.src
+	for i := len(spans) - 1; i >= 0; i-- {
+		src = append(src[:spans[i].start:spans[i].start], src[spans[i].end:]...)
+	}
+	return d.reset(src)
+}
+
+// setInline sets the key rest inside the inline table stored in e.
+func (d *document) setInline(e *entry, rest []string, val any) error {
+	var current any
+	if err := d.Get(e.path, &current); err != nil {
+		return err
+	}
+	m, ok := current.(map[string]any)
+	if !ok {
+		return fmt.Errorf("toml: cannot set %q: %q is not a table",
+			strings.Join(append(e.path, rest...), "."), strings.Join(e.path, "."))
+	}
+	for i, key := range rest[:len(rest)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			if _, exists := m[key]; exists {
+				return fmt.Errorf("toml: cannot set %q: %q is not a table",
+					strings.Join(append(e.path, rest...), "."), strings.Join(append(e.path, rest[:i+1]...), "."))
+			}
+			next = make(map[string]any)
+			m[key] = next
+		}
+		m = next
+	}
+	m[rest[len(rest)-1]] = val
+	return d.splice(e.valueStart, e.valueEnd, formatInline(current))
+}
+
+// deleteInline removes the key rest from the inline table stored in e.
+func (d *document) deleteInline(e *entry, rest []string) error {
+	var current any
+	if err := d.Get(e.path, &current); err != nil {
+		return err
+	}
+	m, _ := current.(map[string]any)
+	for _, key := range rest[:len(rest)-1] {
+		m, _ = m[key].(map[string]any)
+	}
+	if _, ok := m[rest[len(rest)-1]]; !ok {
+		return fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(append(e.path, rest...), "."))
+	}
+	delete(m, rest[len(rest)-1])
+	return d.splice(e.valueStart, e.valueEnd, formatInline(current))
+}
+
+// appendTable writes val as a new [path] table at the end of the document.
+// Only the header of path itself is written, since its parent tables may
+// already be defined and TOML allows a table to be defined once.
+func (d *document) appendTable(path []string, val any) error {
+	var nested any = val
+	for i := len(path) - 1; i >= 0; i-- {
+		nested = map[string]any{path[i]: nested}
+	}
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = d.indent
+	if err := encoder.Encode(nested); err != nil {
+		return err
+	}
+
+	// The encoder writes a header line for each parent, then indents
+	// everything below it by one level per parent.
+	lines := strings.SplitAfter(strings.TrimLeft(buf.String(), "\n"), "\n")[len(path)-1:]
+	outdent := strings.Repeat(d.indent, len(path)-1)
+	src := bytes.TrimRight(d.src, " \t\r\n")
+	out := make([]byte, 0, len(src)+buf.Len()+2)
+	out = append(out, src...)
+	if len(out) > 0 {
+		out = append(out, "\n\n"...)
+	}
+	for _, line := range lines {
+		out = append(out, strings.TrimPrefix(line, outdent)...)
+	}
+	return d.reset(out)
+}
+
+// covers reports whether any table or entry is defined at or below path.
+func (d *document) covers(path []string) bool {
+	for i, t := range d.tables {
+		if i > 0 && hasPrefix(t.path, path) {
+			return true
+		}
+		for _, e := range t.addressable() {
+			if hasPrefix(e.path, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commentsAbove returns the start of the comment lines directly above the
+// line starting at pos.
+func (d *document) commentsAbove(pos int) int {
+	for pos > 0 {
+		prev := bytes.LastIndexByte(d.src[:pos-1], '\n') + 1
+		if !bytes.HasPrefix(bytes.TrimSpace(d.src[prev:pos]), []byte("#")) {
+			break
+		}
+		pos = prev
+	}
+	return pos
+}
+
+func (d *document) splice(start, end int, text string) error {
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+	return d.reset(src)
+}
+
+// reset validates src, replaces the document source and indexes it again.
+func (d *document) reset(src []byte) error {
+	var v map[string]any
+	if _, err := toml.Decode(string(src), &v); err != nil {
+		return err
+	}
+	tables, err := scan(src)
+	if err != nil {
+		return err
+	}
+	d.src, d.tables = src, tables
+	return nil
+}
+
+// scan indexes the tables and entries of a valid TOML document.
+func scan(src []byte) ([]*table, error) {
+	s := &scanner{src: src}
+	cur := &table{}
+	tables := []*table{cur}
+	for {
+		s.skipBlank()
+		if s.pos >= len(src) {
+			return tables, nil
+		}
+		start := s.pos
+		if src[s.pos] == '[' {
+			t := &table{start: start}
+			s.pos++
+			if s.peek('[') {
+				t.array = true
+				s.pos++
+			}
+			path, err := s.key()
+			if err != nil {
+				return nil, err
+			}
+			t.path = path
+			s.pos = s.lineEnd()
+			t.body = s.pos
+			cur = t
+			tables = append(tables, t)
+			continue
+		}
+
+		keys, err := s.key()
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		if !s.peek('=') {
+			return nil, s.errorf("expected '=' after key")
+		}
+		s.pos++
+		s.skipSpace()
+		e := &entry{path: append(append([]string{}, cur.path...), keys...), start: start, valueStart: s.pos}
+		if err := s.value(); err != nil {
+			return nil, err
+		}
+		e.valueEnd = s.pos
+		s.pos = s.lineEnd()
+		e.end = s.pos
+		cur.entries = append(cur.entries, e)
+	}
+}
+
+type scanner struct {
+	src []byte
+	pos int
+}
+
+func (s *scanner) peek(c byte) bool {
+	return s.pos < len(s.src) && s.src[s.pos] == c
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.src) && (s.src[s.pos] == ' ' || s.src[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (s *scanner) skipBlank() {
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		case '#':
+			s.pos = s.lineEnd()
+		default:
+			return
+		}
+	}
+}
+
+// lineEnd returns the offset just past the end of the current line.
+func (s *scanner) lineEnd() int {
+	i := bytes.IndexByte(s.src[s.pos:], '\n')
+	if i < 0 {
+		return len(s.src)
+	}
+	return s.pos + i + 1
+}
+
+// key reads a dotted key, stopping at '=', ']' or the end of the line.
+func (s *scanner) key() ([]string, error) {
+	var keys []string
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.src) {
+			return nil, s.errorf("unexpected end of key")
+		}
+		switch c := s.src[s.pos]; c {
+		case '"', '\'':
+			start := s.pos
+			if err := s.str(); err != nil {
+				return nil, err
+			}
+			raw := string(s.src[start:s.pos])
+			if c == '\'' {
+				keys = append(keys, raw[1:len(raw)-1])
+			} else {
+				key, err := strconv.Unquote(raw)
+				if err != nil {
+					return nil, s.errorf("invalid quoted key %s", raw)
+				}
+				keys = append(keys, key)
+			}
+		default:
+			start := s.pos
+			for s.pos < len(s.src) && isBareKey(s.src[s.pos]) {
+				s.pos++
+			}
+			if s.pos == start {
+				return nil, s.errorf("invalid key")
+			}
+			keys = append(keys, string(s.src[start:s.pos]))
+		}
+		s.skipSpace()
+		if !s.peek('.') {
+			return keys, nil
+		}
+		s.pos++
+	}
+}
+
+var localDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d`)
+
+// value skips over a value.
+func (s *scanner) value() error {
+	if s.pos >= len(s.src) {
+		return s.errorf("missing value")
+	}
+	switch s.src[s.pos] {
+	case '"', '\'':
+		return s.str()
+	case '[', '{':
+		return s.nested()
+	}
+	if localDate.Match(s.src[s.pos:]) {
+		s.pos += len("0000-00-00 ")
+	}
+	for s.pos < len(s.src) && !strings.ContainsRune(" \t\r\n#,]}", rune(s.src[s.pos])) {
+		s.pos++
+	}
+	return nil
+}
+
+// nested skips an array or inline table, including nested values.
+func (s *scanner) nested() error {
+	depth := 0
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				s.pos++
+				return nil
+			}
+		case '"', '\'':
+			if err := s.str(); err != nil {
+				return err
+			}
+			continue
+		case '#':
+			s.pos = s.lineEnd()
+			continue
+		}
+		s.pos++
+	}
+	return s.errorf("unterminated array or inline table")
+}
+
+// str skips a basic, literal or multi-line string.
+func (s *scanner) str() error {
+	q := s.src[s.pos]
+	delim := []byte{q}
+	if bytes.HasPrefix(s.src[s.pos:], []byte{q, q, q}) {
+		delim = []byte{q, q, q}
+	}
+	s.pos += len(delim)
+	for s.pos < len(s.src) {
+		if q == '"' && s.src[s.pos] == '\\' {
+			s.pos += 2
+			continue
+		}
+		if bytes.HasPrefix(s.src[s.pos:], delim) {
+			s.pos += len(delim)
+			// Up to two quotes may directly precede a multi-line closing
+			// delimiter.
+			for n := 0; len(delim) == 3 && n < 2 && s.peek(q); n++ {
+				s.pos++
+			}
+			return nil
+		}
+		s.pos++
+	}
+	return s.errorf("unterminated string")
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	line := 1 + bytes.Count(s.src[:s.pos], []byte{'\n'})
+	return fmt.Errorf("toml: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// normalize converts value into the generic form produced by decoding
+// TOML, so that struct tags and custom marshalers are honored.
+func normalize(value any) (any, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]any{"v": value}); err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if _, err := toml.Decode(buf.String(), &m); err != nil {
+		return nil, err
+	}
+	return m["v"], nil
+}
+
+// formatInline formats a normalized value on a single line, using inline
+// tables for maps.
+func formatInline(v any) string {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			return "{}"
+		}
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = formatKey([]string{k}) + " = " + formatInline(rv.MapIndex(reflect.ValueOf(k)).Interface())
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	case reflect.Slice:
+		parts := make([]string, rv.Len())
+		for i := range parts {
+			parts[i] = formatInline(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+
+	var buf bytes.Buffer
+	_ = toml.NewEncoder(&buf).Encode(map[string]any{"v": v})
+	return strings.TrimSuffix(strings.TrimPrefix(buf.String(), "v = "), "\n")
+}
+
+// formatKey formats a dotted key, quoting segments that are not bare keys.
+func formatKey(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k
+		if k == "" || strings.IndexFunc(k, func(r rune) bool { return r > 0x7f || !isBareKey(byte(r)) }) >= 0 {
+			parts[i] = strconv.Quote(k)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func insertAt(src []byte, pos int, text string) []byte {
+	out := make([]byte, 0, len(src)+len(text))
+	out = append(out, src[:pos]...)
+	out = append(out, text...)
+	return append(out, src[pos:]...)
+}
+
+func equalPath(a, b []string) bool {
+	return len(a) == len(b) && hasPrefix(a, b)
+}
+
+// hasPrefix reports whether path starts with prefix.
+func hasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
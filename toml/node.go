@@ -0,0 +1,229 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/scalar"
+	"github.com/nuln/conf/internal/textpos"
+)
+
+// DecodeNode decodes data into a tree that keeps the order in which keys
+// and tables are defined. Local dates and times keep their TOML form.
+// Positions are those of the key or table header defining each value.
+func (c *tomlCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	var v map[string]any
+	md, err := toml.Decode(string(data), &v)
+	if err != nil {
+		return nil, err
+	}
+	n, err := conf.NewNode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]int)
+	for i, key := range md.Keys() {
+		if _, ok := order[key.String()]; !ok {
+			order[key.String()] = i
+		}
+	}
+	sortFields(n, nil, order)
+
+	tables, err := scan(data)
+	if err != nil {
+		return nil, err
+	}
+	setPositions(n, tables, textpos.NewIndex(data))
+	return n, nil
+}
+
+// setPositions records the positions of the tables and keys defined in
+// tables on the corresponding nodes of root. Values inside inline tables
+// and arrays have no position of their own.
+func setPositions(root *conf.Node, tables []*table, index textpos.Index) {
+	// seen counts the elements of each array of tables defined so far;
+	// keys below an array refer to its latest element.
+	seen := make(map[string]int)
+	lookup := func(path []string) *conf.Node {
+		n := root
+		for i, key := range path {
+			n = n.Get(key)
+			if n == nil {
+				return nil
+			}
+			if n.Kind == conf.ListNode {
+				idx := seen[toml.Key(path[:i+1]).String()] - 1
+				if idx < 0 || idx >= len(n.Items) {
+					return nil
+				}
+				n = n.Items[idx]
+			}
+		}
+		return n
+	}
+
+	for i, t := range tables {
+		if i > 0 {
+			if t.array {
+				seen[toml.Key(t.path).String()]++
+			}
+			if n := lookup(t.path); n != nil {
+				n.Line, n.Column = index.Position(t.start)
+			}
+		}
+		for _, e := range t.entries {
+			if n := lookup(e.path); n != nil {
+				n.Line, n.Column = index.Position(e.start)
+			}
+		}
+	}
+}
+
+// sortFields orders the fields of maps within n, which is at path, by
+// their position in order. Elements of arrays of tables share the key of
+// the array.
+func sortFields(n *conf.Node, path toml.Key, order map[string]int) {
+	switch n.Kind {
+	case conf.MapNode:
+		rank := func(key string) int {
+			return order[append(path[:len(path):len(path)], key).String()]
+		}
+		sort.SliceStable(n.Fields, func(i, j int) bool {
+			return rank(n.Fields[i].Key) < rank(n.Fields[j].Key)
+		})
+		for _, f := range n.Fields {
+			sortFields(f.Value, append(path[:len(path):len(path)], f.Key), order)
+		}
+	case conf.ListNode:
+		for _, item := range n.Items {
+			sortFields(item, path, order)
+		}
+	}
+}
+
+// EncodeNode encodes n, which must be a map, keeping the order of its keys
+// except that TOML requires plain keys to precede the tables in each
+// table. Null values, which TOML cannot represent, are rejected with an
+// error naming their key.
+func (c *tomlCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	if n.Kind != conf.MapNode {
+		return nil, fmt.Errorf("toml: top-level value must be a table, not %s", n.Kind)
+	}
+	w := &nodeWriter{indent: c.indent}
+	if err := w.table(nil, n); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+var _ conf.NodeCodec = (*tomlCodec)(nil)
+
+type nodeWriter struct {
+	buf    bytes.Buffer
+	indent string
+}
+
+// table writes the body of the table at path, followed by its subtables,
+// using the same layout as the toml encoder.
+func (w *nodeWriter) table(path []string, n *conf.Node) error {
+	indent := strings.Repeat(w.indent, len(path))
+	for _, f := range n.Fields {
+		if isTable(f.Value) || isTableArray(f.Value) {
+			continue
+		}
+		value, err := inline(f.Value)
+		if err != nil {
+			return fmt.Errorf("toml: %s: %w", formatKey(append(path, f.Key)), err)
+		}
+		fmt.Fprintf(&w.buf, "%s%s = %s\n", indent, formatKey([]string{f.Key}), value)
+	}
+
+	for _, f := range n.Fields {
+		sub := append(path[:len(path):len(path)], f.Key)
+		header := strings.Repeat(w.indent, len(sub)-1)
+		switch {
+		case isTable(f.Value):
+			w.separate(sub)
+			fmt.Fprintf(&w.buf, "%s[%s]\n", header, formatKey(sub))
+			if err := w.table(sub, f.Value); err != nil {
+				return err
+			}
+		case isTableArray(f.Value):
+			for _, item := range f.Value.Items {
+				w.separate(sub)
+				fmt.Fprintf(&w.buf, "%s[[%s]]\n", header, formatKey(sub))
+				if err := w.table(sub, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// separate writes the blank line that precedes top-level tables.
+func (w *nodeWriter) separate(path []string) {
+	if len(path) == 1 && w.buf.Len() > 0 {
+		w.buf.WriteByte('\n')
+	}
+}
+
+func isTable(n *conf.Node) bool {
+	return n.Kind == conf.MapNode
+}
+
+func isTableArray(n *conf.Node) bool {
+	if n.Kind != conf.ListNode || len(n.Items) == 0 {
+		return false
+	}
+	for _, item := range n.Items {
+		if item.Kind != conf.MapNode {
+			return false
+		}
+	}
+	return true
+}
+
+// inline formats n as an inline value.
+func inline(n *conf.Node) (string, error) {
+	switch n.Kind {
+	case conf.NullNode:
+		return "", fmt.Errorf("null values are not supported")
+	case conf.MapNode:
+		parts := make([]string, 0, len(n.Fields))
+		for _, f := range n.Fields {
+			value, err := inline(f.Value)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", formatKey([]string{f.Key}), err)
+			}
+			parts = append(parts, formatKey([]string{f.Key})+" = "+value)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	case conf.ListNode:
+		parts := make([]string, len(n.Items))
+		for i, item := range n.Items {
+			value, err := inline(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = value
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case conf.TimeNode:
+		return scalar.FormatTime(n.Value.(time.Time)), nil
+	case conf.BytesNode:
+		return formatInline(base64.StdEncoding.EncodeToString(n.Value.([]byte))), nil
+	}
+	return formatInline(n.Value), nil
+}
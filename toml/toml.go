@@ -16,16 +16,34 @@ func init() {
 	conf.Register("toml", New())
 }
 
-// New returns a new TOML codec.
-func New() conf.Codec {
-	return &tomlCodec{}
+// Option configures a TOML codec created by New.
+type Option func(*tomlCodec)
+
+// Indent sets the string used to indent nested tables and arrays when
+// encoding. The default is two spaces; use "" for no indentation.
+func Indent(indent string) Option {
+	return func(c *tomlCodec) {
+		c.indent = indent
+	}
 }
 
-type tomlCodec struct{}
+// New returns a new TOML codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &tomlCodec{indent: "  "}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type tomlCodec struct {
+	indent string
+}
 
 func (c *tomlCodec) Encode(v any) ([]byte, error) {
 	var buf bytes.Buffer
 	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = c.indent
 	if err := encoder.Encode(v); err != nil {
 		return nil, err
 	}
@@ -37,8 +55,19 @@ func (c *tomlCodec) Decode(data []byte, v any) error {
 	return err
 }
 
+// Sniff reports whether data is a valid TOML document defining at least
+// one key.
+func (c *tomlCodec) Sniff(data []byte) bool {
+	var v map[string]any
+	md, err := toml.Decode(string(data), &v)
+	return err == nil && len(md.Keys()) > 0
+}
+
 func (c *tomlCodec) Extensions() []string {
 	return []string{".toml"}
 }
 
-var _ conf.Codec = (*tomlCodec)(nil)
+var (
+	_ conf.Codec   = (*tomlCodec)(nil)
+	_ conf.Sniffer = (*tomlCodec)(nil)
+)
@@ -25,3 +25,124 @@ func TestTOMLRegistration(t *testing.T) {
 		t.Error("toml should be registered via init()")
 	}
 }
+
+func TestTOMLIndent(t *testing.T) {
+	v := map[string]any{"db": map[string]any{"host": "localhost"}}
+
+	data, err := ct.New(ct.Indent("")).Encode(v)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "[db]\nhost = \"localhost\"\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestTOMLEdit(t *testing.T) {
+	input := `# service settings
+name = "billing" # display name
+
+[database]
+host = "localhost"
+# connection pool
+pool = 10
+ports = [
+  5432,
+  5433,
+]
+`
+	want := `# service settings
+name = "payments" # display name
+debug = true
+
+[database]
+host = "localhost"
+ports = [6432]
+user = "admin"
+`
+
+	doc, err := ct.New().(conf.Editor).Edit([]byte(input))
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if err := doc.Set([]string{"name"}, "payments"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Set([]string{"debug"}, true); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Delete([]string{"database", "pool"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := doc.Set([]string{"database", "ports"}, []int{6432}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Set([]string{"database", "user"}, "admin"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", got, want)
+	}
+
+	var host string
+	if err := doc.Get([]string{"database", "host"}, &host); err != nil || host != "localhost" {
+		t.Errorf("Get: got %q, %v", host, err)
+	}
+}
+
+func TestTOMLEditNestedTable(t *testing.T) {
+	input := "[database]\nhost = \"localhost\"\n"
+	want := "[database]\nhost = \"localhost\"\n\n[database.opts]\n  a = 1\n"
+
+	doc, err := ct.New().(conf.Editor).Edit([]byte(input))
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if err := doc.Set([]string{"database", "opts"}, map[string]any{"a": 1}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTOMLNodeRoundTrip(t *testing.T) {
+	src := "title = \"x\"\nday = 2024-01-02\n\n[zeta]\n  b = 1\n  a = 2.0\n\n[alpha]\n  q = [1, 2]\n\n[[servers]]\n  name = \"a\"\n  [servers.tls]\n    on = true\n\n[[servers]]\n  name = \"b\"\n"
+
+	nc := ct.New().(conf.NodeCodec)
+	n, err := nc.DecodeNode([]byte(src))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	if got := n.Get("zeta").Get("a").Kind; got != conf.FloatNode {
+		t.Errorf("zeta.a: got %s, want float", got)
+	}
+
+	data, err := nc.EncodeNode(n)
+	if err != nil {
+		t.Fatalf("EncodeNode failed: %v", err)
+	}
+	if string(data) != src {
+		t.Errorf("got:\n%s\nwant:\n%s", data, src)
+	}
+}
+
+func TestTOMLNodePositions(t *testing.T) {
+	src := "name = \"x\"\n\n[[servers]]\nhost = \"a\"\n\n[[servers]]\nhost = \"b\"\n"
+
+	n, err := ct.New().(conf.NodeCodec).DecodeNode([]byte(src))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	servers := n.Get("servers")
+	if len(servers.Items) != 2 {
+		t.Fatalf("got %d servers, want 2", len(servers.Items))
+	}
+	if got := servers.Items[1].Get("host").Line; got != 7 {
+		t.Errorf("servers[1].host: got line %d, want 7", got)
+	}
+	if got := servers.Items[1].Line; got != 6 {
+		t.Errorf("servers[1]: got line %d, want 6", got)
+	}
+}
@@ -0,0 +1,165 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration written as text such as "1h30m" in every
+// format, including those that would otherwise write durations as
+// nanoseconds. It decodes from such text, or from integer nanoseconds
+// where conf decodes the value itself.
+type Duration time.Duration
+
+// String returns d like time.Duration.String, without trailing zero units:
+// "1h30m" rather than "1h30m0s".
+func (d Duration) String() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	x, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(x)
+	return nil
+}
+
+// ByteSize is a number of bytes written as text such as "2GiB" in every
+// format. It decodes from text accepted by ParseByteSize, or from integers
+// where conf decodes the value itself.
+type ByteSize int64
+
+// Byte sizes in IEC units.
+const (
+	Byte ByteSize = 1 << (10 * iota)
+	KiB
+	MiB
+	GiB
+	TiB
+	PiB
+	EiB
+)
+
+var sizeUnits = []struct {
+	size ByteSize
+	name string
+}{
+	{EiB, "EiB"}, {PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+}
+
+// String returns b in the largest IEC unit that divides it exactly, such as
+// "2GiB" or "1536KiB", or in bytes as "100B".
+func (b ByteSize) String() string {
+	if b != 0 {
+		for _, u := range sizeUnits {
+			if b%u.size == 0 {
+				return strconv.FormatInt(int64(b/u.size), 10) + u.name
+			}
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Plain integers are
+// taken as bytes.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := string(text)
+	x, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		if x, err = ParseByteSize(s); err != nil {
+			return err
+		}
+	}
+	*b = ByteSize(x)
+	return nil
+}
+
+// byteUnits maps the units of byte sizes to their multipliers.
+var byteUnits = map[string]float64{
+	"b":  1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15, "eb": 1e18,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40, "pib": 1 << 50, "eib": 1 << 60,
+	"k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40,
+}
+
+// ParseByteSize parses a byte size such as "512MiB", "1.5GB" or "64k".
+// Units are case-insensitive. SI units such as kB are powers of 1000, while
+// IEC units such as KiB and single-letter units are powers of 1024. Fields
+// of type ByteSize accept byte sizes; plain integer fields do not.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if i <= 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, s[i:])
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	size := f * unit
+	if size >= math.MaxInt64 || size < math.MinInt64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+	return int64(size), nil
+}
+
+// Percent is a fraction written as a percentage such as "75%" in every
+// format: Percent(0.75) is "75%". It decodes only from such text, since a
+// bare 75 could mean either.
+type Percent float64
+
+// String returns p as a percentage, such as "75%" or "12.5%".
+func (p Percent) String() string {
+	// Rounding drops the noise of the multiplication, as in 0.07*100.
+	x := math.Round(float64(p)*100*1e9) / 1e9
+	return strconv.FormatFloat(x, 'f', -1, 64) + "%"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Percent) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	num, ok := strings.CutSuffix(s, "%")
+	if !ok {
+		return fmt.Errorf("invalid percentage %q: want a number followed by %%", s)
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("invalid percentage %q", s)
+	}
+	*p = Percent(f / 100)
+	return nil
+}
@@ -0,0 +1,101 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+
+	"github.com/nuln/conf"
+)
+
+// DecodeNode decodes the content of the root element into a tree that
+// keeps the order of child elements. As with decoding into map[string]any,
+// attributes become fields, repeated elements become lists and all
+// scalars are strings.
+func (c *xmlCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return node(root), nil
+}
+
+// EncodeNode encodes n as the content of the root element, keeping the
+// order of its fields.
+func (c *xmlCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := encodeNode(enc, c.root, n); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+var _ conf.NodeCodec = (*xmlCodec)(nil)
+
+// node converts el like generic, recording element positions.
+func node(el *element) *conf.Node {
+	n := &conf.Node{Line: el.line, Column: el.column}
+	if len(el.attrs) == 0 && len(el.children) == 0 {
+		n.Kind, n.Value = conf.StringNode, el.text
+		return n
+	}
+	n.Kind = conf.MapNode
+	add := func(key string, val *conf.Node) {
+		prev := n.Get(key)
+		switch {
+		case prev == nil:
+			n.Fields = append(n.Fields, conf.Field{Key: key, Value: val})
+		case prev.Kind == conf.ListNode:
+			// Elements never decode to lists, so this is a repeated name.
+			prev.Items = append(prev.Items, val)
+		default:
+			for i := range n.Fields {
+				if n.Fields[i].Key == key {
+					n.Fields[i].Value = &conf.Node{Kind: conf.ListNode, Items: []*conf.Node{prev, val}}
+				}
+			}
+		}
+	}
+	for _, attr := range el.attrs {
+		add(attr.Name.Local, &conf.Node{Kind: conf.StringNode, Value: attr.Value, Line: el.line, Column: el.column})
+	}
+	for _, child := range el.children {
+		add(child.name, node(child))
+	}
+	return n
+}
+
+// encodeNode writes n as one or more elements named name.
+func encodeNode(enc *xml.Encoder, name string, n *conf.Node) error {
+	switch n.Kind {
+	case conf.MapNode:
+		start, err := startElement(name)
+		if err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, f := range n.Fields {
+			if err := encodeNode(enc, f.Key, f.Value); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case conf.ListNode:
+		for _, item := range n.Items {
+			if err := encodeNode(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return encodeElement(enc, name, reflect.ValueOf(n.Value))
+}
@@ -0,0 +1,743 @@
+// Package xml provides an XML codec for the conf package.
+// Import this package to register the "xml" codec:
+//
+//	import _ "github.com/nuln/conf/xml"
+//
+// The root element holds the document; its name is ignored when decoding.
+// Child elements and attributes both map to struct fields or map keys, and
+// repeated elements with the same name populate slices. Field names are
+// taken from the xml tag, falling back to the json, yaml and toml tags, so
+// structs written for the other codecs decode without changes. Fields
+// tagged `xml:"name,attr"` are encoded as attributes, and a field tagged
+// `xml:",chardata"` receives the element's text. Types implementing
+// xml.Marshaler and xml.Unmarshaler, or their attribute counterparts,
+// encode and decode themselves. Nested names such as `xml:"db>host"` are
+// not supported and fail to encode or decode.
+//
+// Values are decoded from their text representation; when the target is an
+// interface, elements become map[string]any and text becomes string.
+package xml
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/fields"
+)
+
+func init() {
+	conf.Register("xml", New())
+}
+
+// DefaultRoot is the root element name used by Encode when the value
+// does not declare one through an XMLName field.
+const DefaultRoot = "config"
+
+// Option configures an XML codec created by New.
+type Option func(*xmlCodec)
+
+// Root sets the root element name written by Encode.
+func Root(name string) Option {
+	return func(c *xmlCodec) {
+		c.root = name
+	}
+}
+
+// New returns a new XML codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &xmlCodec{root: DefaultRoot}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type xmlCodec struct {
+	root string
+}
+
+func (c *xmlCodec) Encode(v any) ([]byte, error) {
+	rv := indirect(reflect.ValueOf(v))
+	root := c.root
+	if rv.Kind() == reflect.Struct {
+		if f, ok := rv.Type().FieldByName("XMLName"); ok {
+			if name, _, _ := strings.Cut(f.Tag.Get("xml"), ","); name != "" {
+				root = name
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := encodeElement(enc, root, rv); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func (c *xmlCodec) Decode(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("xml: decode target must be a non-nil pointer, got %T", v)
+	}
+	root, err := parse(data)
+	if err != nil {
+		return err
+	}
+	return decodeElement(root, rv.Elem())
+}
+
+// Sniff reports whether data starts with an XML declaration or element.
+func (c *xmlCodec) Sniff(data []byte) bool {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) < 2 || data[0] != '<' {
+		return false
+	}
+	_, err := parse(data)
+	return err == nil
+}
+
+func (c *xmlCodec) Extensions() []string {
+	return []string{".xml"}
+}
+
+var (
+	_ conf.Codec   = (*xmlCodec)(nil)
+	_ conf.Sniffer = (*xmlCodec)(nil)
+)
+
+// element is a parsed XML element.
+type element struct {
+	name     string
+	attrs    []xml.Attr
+	children []*element
+	text     string
+
+	line, column int
+}
+
+// parse reads data into an element tree and returns the root element.
+func parse(data []byte) (*element, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		root  *element
+		stack []*element
+	)
+	for {
+		line, column := dec.InputPos()
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil && len(stack) == 0 {
+				return nil, errors.New("xml: multiple root elements")
+			}
+			el := &element{name: t.Name.Local, attrs: t.Attr, line: line, column: column}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			} else {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			el := stack[len(stack)-1]
+			el.text = strings.TrimSpace(el.text)
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("xml: text outside of root element")
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("xml: no root element")
+	}
+	return root, nil
+}
+
+// field describes how a struct field maps to XML.
+type field struct {
+	name      string
+	index     []int
+	attr      bool
+	chardata  bool
+	omitEmpty bool
+}
+
+var nameType = reflect.TypeFor[xml.Name]()
+
+// structFields returns the XML mapping of the exported fields of t, named
+// as by the other codecs. The attr and chardata options are only
+// recognized in the xml tag.
+func structFields(t reflect.Type) ([]field, error) {
+	var out []field
+	for _, f := range fields.Own(t, "xml") {
+		if f.Type == nameType {
+			continue
+		}
+		name := f.Keys[0]
+		if f.Implicit {
+			name = f.Name
+		}
+		if strings.Contains(name, ">") {
+			return nil, fmt.Errorf("xml: field %s: nested element names such as %q are not supported", f.Name, name)
+		}
+		_, opts, _ := strings.Cut(f.Tag.Get("xml"), ",")
+		opts = "," + opts + ","
+		out = append(out, field{
+			name:      name,
+			index:     f.Index,
+			attr:      strings.Contains(opts, ",attr,"),
+			chardata:  strings.Contains(opts, ",chardata,"),
+			omitEmpty: f.Has("omitempty"),
+		})
+	}
+	return out, nil
+}
+
+// lookupField finds the field named name, preferring an exact match.
+func lookupField(fs []field, name string) (field, bool) {
+	for _, f := range fs {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range fs {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// fieldByIndex returns the field at index, allocating nil embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func decodeElement(el *element, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeElement(el, v.Elem())
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(xml.Unmarshaler); ok {
+			return unmarshalXML(el, u)
+		}
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return decodeText(el.text, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("xml: cannot decode element <%s> into %s", el.name, v.Type())
+		}
+		v.Set(reflect.ValueOf(generic(el)))
+		return nil
+	case reflect.Struct:
+		return decodeStruct(el, v)
+	case reflect.Map:
+		return decodeMap(el, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeElement(el, elem); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, elem))
+			return nil
+		}
+	}
+	return decodeText(el.text, v)
+}
+
+func decodeStruct(el *element, v reflect.Value) error {
+	fs, err := structFields(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, attr := range el.attrs {
+		f, ok := lookupField(fs, attr.Name.Local)
+		if !ok {
+			continue
+		}
+		if err := decodeAttr(attr, fieldByIndex(v, f.index)); err != nil {
+			return fmt.Errorf("xml: attribute %q of <%s>: %w", attr.Name.Local, el.name, err)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, child := range el.children {
+		f, ok := lookupField(fs, child.name)
+		if !ok {
+			continue
+		}
+		fv := fieldByIndex(v, f.index)
+		if !seen[f.name] && isList(fv.Type()) {
+			// Replace the slice rather than appending to its current
+			// contents, as encoding/json does.
+			fv.SetZero()
+		}
+		seen[f.name] = true
+		if err := decodeElement(child, fv); err != nil {
+			return err
+		}
+	}
+	for _, f := range fs {
+		if f.chardata {
+			return decodeText(el.text, fieldByIndex(v, f.index))
+		}
+	}
+	return nil
+}
+
+// unmarshalXML passes el to the UnmarshalXML method of u, writing it out
+// again for an xml.Decoder to read.
+func unmarshalXML(el *element, u xml.Unmarshaler) error {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := writeElement(enc, el); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	dec := xml.NewDecoder(&buf)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return u.UnmarshalXML(dec, tok.(xml.StartElement))
+}
+
+// writeElement writes el and its children as tokens. The text of an
+// element comes before its children.
+func writeElement(enc *xml.Encoder, el *element) error {
+	start := xml.StartElement{Name: xml.Name{Local: el.name}, Attr: el.attrs}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if el.text != "" {
+		if err := enc.EncodeToken(xml.CharData(el.text)); err != nil {
+			return err
+		}
+	}
+	for _, child := range el.children {
+		if err := writeElement(enc, child); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// decodeAttr stores the attribute attr into v.
+func decodeAttr(attr xml.Attr, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeAttr(attr, v.Elem())
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(xml.UnmarshalerAttr); ok {
+			return u.UnmarshalXMLAttr(attr)
+		}
+	}
+	return decodeText(attr.Value, v)
+}
+
+func decodeMap(el *element, v reflect.Value) error {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("xml: cannot decode element <%s> into %s", el.name, t)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	seen := make(map[string]bool)
+	set := func(key string, decode func(reflect.Value) error) error {
+		k := reflect.ValueOf(key).Convert(t.Key())
+		elem := reflect.New(t.Elem()).Elem()
+		existing := v.MapIndex(k)
+		if !seen[key] {
+			// Values already in the map are replaced, not added to.
+			existing = reflect.Value{}
+		}
+		seen[key] = true
+		if existing.IsValid() && isList(t.Elem()) {
+			elem.Set(existing)
+		}
+		if err := decode(elem); err != nil {
+			return err
+		}
+		if existing.IsValid() && t.Elem().Kind() == reflect.Interface {
+			// Repeated names collect into []any, as in generic.
+			list, ok := existing.Interface().([]any)
+			if !ok {
+				list = []any{existing.Interface()}
+			}
+			elem = reflect.ValueOf(append(list, elem.Interface()))
+		}
+		v.SetMapIndex(k, elem)
+		return nil
+	}
+	for _, attr := range el.attrs {
+		err := set(attr.Name.Local, func(elem reflect.Value) error {
+			return decodeText(attr.Value, elem)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range el.children {
+		err := set(child.name, func(elem reflect.Value) error {
+			return decodeElement(child, elem)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isList reports whether values of t collect repeated elements.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// decodeText stores the text s into v.
+func decodeText(s string, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeText(s, v.Elem())
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(s))
+			return nil
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return nil
+		}
+	}
+
+	if s == "" {
+		v.SetZero()
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("xml: cannot decode text into %s", v.Type())
+	}
+	return nil
+}
+
+// generic converts el into map[string]any, or into its text if it has
+// neither attributes nor children. Repeated names are collected in []any.
+func generic(el *element) any {
+	if len(el.attrs) == 0 && len(el.children) == 0 {
+		return el.text
+	}
+	m := make(map[string]any, len(el.attrs)+len(el.children))
+	add := func(key string, val any) {
+		switch prev := m[key].(type) {
+		case nil:
+			m[key] = val
+		case []any:
+			m[key] = append(prev, val)
+		default:
+			m[key] = []any{prev, val}
+		}
+	}
+	for _, attr := range el.attrs {
+		add(attr.Name.Local, attr.Value)
+	}
+	for _, child := range el.children {
+		add(child.name, generic(child))
+	}
+	return m
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// encodeElement writes v as one or more elements named name.
+func encodeElement(enc *xml.Encoder, name string, v reflect.Value) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	start, err := startElement(name)
+	if err != nil {
+		return err
+	}
+
+	if m, ok := as[xml.Marshaler](v); ok {
+		return enc.EncodeElement(m, start)
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		return enc.EncodeElement(string(text), start)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return encodeStruct(enc, start, v)
+	case reflect.Map:
+		return encodeMap(enc, start, v)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Bytes panics for arrays that are not addressable.
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return enc.EncodeElement(string(data), start)
+		}
+		for i := range v.Len() {
+			if err := encodeElement(enc, name, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	text, err := scalarText(v)
+	if err != nil {
+		return err
+	}
+	return enc.EncodeElement(text, start)
+}
+
+// startElement returns the start of an element named name, which must be
+// a valid XML name.
+func startElement(name string) (xml.StartElement, error) {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && r != ':' &&
+			(i == 0 || !unicode.IsDigit(r) && r != '-' && r != '.') {
+			return xml.StartElement{}, fmt.Errorf("xml: invalid element name %q", name)
+		}
+	}
+	if name == "" {
+		return xml.StartElement{}, errors.New("xml: empty element name")
+	}
+	return xml.StartElement{Name: xml.Name{Local: name}}, nil
+}
+
+func encodeStruct(enc *xml.Encoder, start xml.StartElement, v reflect.Value) error {
+	fs, err := structFields(v.Type())
+	if err != nil {
+		return err
+	}
+	var chardata string
+	var children []field
+	for _, f := range fs {
+		fv, ok := fieldValue(v, f.index)
+		if !ok || f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		switch {
+		case f.attr:
+			if m, ok := as[xml.MarshalerAttr](indirect(fv)); ok {
+				attr, err := m.MarshalXMLAttr(xml.Name{Local: f.name})
+				if err != nil {
+					return err
+				}
+				if attr.Name.Local != "" {
+					start.Attr = append(start.Attr, attr)
+				}
+				continue
+			}
+			text, err := scalarText(indirect(fv))
+			if err != nil {
+				return err
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: f.name}, Value: text})
+		case f.chardata:
+			text, err := scalarText(indirect(fv))
+			if err != nil {
+				return err
+			}
+			chardata = text
+		default:
+			children = append(children, f)
+		}
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if chardata != "" {
+		if err := enc.EncodeToken(xml.CharData(chardata)); err != nil {
+			return err
+		}
+	}
+	for _, f := range children {
+		fv, _ := fieldValue(v, f.index)
+		if err := encodeElement(enc, f.name, fv); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func encodeMap(enc *xml.Encoder, start xml.StartElement, v reflect.Value) error {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key().Interface())
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := encodeElement(enc, key, values[key]); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// fieldValue returns the field at index, reporting false if it is
+// reached through a nil embedded pointer.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func scalarText(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("xml: cannot encode %s as text", v.Type())
+}
+
+// as returns v as a T if v, or a pointer to it, implements T.
+func as[T any](v reflect.Value) (T, bool) {
+	if !v.IsValid() {
+		var zero T
+		return zero, false
+	}
+	if v.CanAddr() {
+		if t, ok := v.Addr().Interface().(T); ok {
+			return t, true
+		}
+	}
+	t, ok := v.Interface().(T)
+	return t, ok
+}
+
+// indirect dereferences pointers and interfaces, returning the zero Value
+// for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,266 @@
+package xml_test
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/conftest"
+	cx "github.com/nuln/conf/xml"
+)
+
+func TestXML(t *testing.T) {
+	conftest.Suite(t, cx.New())
+}
+
+func TestXMLRegistration(t *testing.T) {
+	available := conf.Available()
+	found := false
+	for _, name := range available {
+		if name == "xml" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("xml should be registered via init()")
+	}
+}
+
+func TestXMLAttributesAndElements(t *testing.T) {
+	input := []byte(`<?xml version="1.0"?>
+<service name="billing">
+  <port>8080</port>
+  <tags>web</tags>
+  <tags>api</tags>
+  <database host="db.local" port="5432"/>
+</service>`)
+
+	var cfg struct {
+		Name     string   `json:"name"`
+		Port     int      `yaml:"port"`
+		Tags     []string `toml:"tags"`
+		Database struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"database"`
+	}
+	if err := cx.New().Decode(input, &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if cfg.Name != "billing" {
+		t.Errorf("Name: got %q", cfg.Name)
+	}
+	if cfg.Port != 8080 {
+		t.Errorf("Port: got %d", cfg.Port)
+	}
+	if len(cfg.Tags) != 2 || cfg.Tags[0] != "web" || cfg.Tags[1] != "api" {
+		t.Errorf("Tags: got %v", cfg.Tags)
+	}
+	if cfg.Database.Host != "db.local" || cfg.Database.Port != 5432 {
+		t.Errorf("Database: got %+v", cfg.Database)
+	}
+}
+
+func TestXMLEncodeAttrAndRoot(t *testing.T) {
+	type server struct {
+		ID   string `xml:"id,attr" json:"id"`
+		Host string `json:"host"`
+	}
+
+	data, err := cx.New(cx.Root("servers")).Encode(map[string]any{
+		"server": []server{{ID: "a", Host: "one"}, {ID: "b", Host: "two"}},
+	})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	out := string(data)
+	for _, want := range []string{"<servers>", `<server id="a">`, "<host>two</host>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("encoded output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestXMLDecodeGeneric(t *testing.T) {
+	var v map[string]any
+	if err := cx.New().Decode([]byte(`<c><a>1</a><a>2</a><b x="y"/></c>`), &v); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if a, ok := v["a"].([]any); !ok || len(a) != 2 || a[0] != "1" {
+		t.Errorf("a: got %#v", v["a"])
+	}
+	if b, ok := v["b"].(map[string]any); !ok || b["x"] != "y" {
+		t.Errorf("b: got %#v", v["b"])
+	}
+}
+
+func TestXMLNode(t *testing.T) {
+	src := "<config>\n  <zone>eu</zone>\n  <host>a</host>\n  <host>b</host>\n</config>"
+
+	nc := cx.New().(conf.NodeCodec)
+	n, err := nc.DecodeNode([]byte(src))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	if n.Fields[0].Key != "zone" || len(n.Get("host").Items) != 2 {
+		t.Fatalf("unexpected tree: %+v", n)
+	}
+	if got := n.Get("host").Items[1].Line; got != 4 {
+		t.Errorf("second host: got line %d, want 4", got)
+	}
+
+	data, err := nc.EncodeNode(n)
+	if err != nil {
+		t.Fatalf("EncodeNode failed: %v", err)
+	}
+	if !strings.Contains(string(data), "<zone>eu</zone>\n  <host>a</host>\n  <host>b</host>") {
+		t.Errorf("order not kept:\n%s", data)
+	}
+}
+
+func TestXMLConfTag(t *testing.T) {
+	type server struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+	type config struct {
+		Name    string   `conf:"name"`
+		Debug   bool     `conf:"debug"`
+		Servers []server `conf:"server"`
+	}
+	original := config{Name: "app", Debug: true, Servers: []server{{"a", 1}}}
+
+	data, err := conf.SaveToBytes(original, "xml")
+	if err != nil {
+		t.Fatalf("SaveToBytes failed: %v", err)
+	}
+	if !strings.Contains(string(data), "<host>a</host>") {
+		t.Errorf("unexpected output:\n%s", data)
+	}
+
+	// A single repeated element decodes into a list of one.
+	var decoded config
+	if err := conf.LoadFromBytes(data, "xml", &decoded); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if decoded.Name != "app" || !decoded.Debug || len(decoded.Servers) != 1 || decoded.Servers[0] != original.Servers[0] {
+		t.Errorf("got %+v, want %+v", decoded, original)
+	}
+}
+
+func TestXMLDuration(t *testing.T) {
+	type config struct {
+		Timeout time.Duration `conf:"timeout"`
+	}
+	data, err := conf.SaveToBytes(config{Timeout: 3 * time.Second}, "xml")
+	if err != nil {
+		t.Fatalf("SaveToBytes failed: %v", err)
+	}
+	var decoded config
+	if err := conf.LoadFromBytes(data, "xml", &decoded); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v\n%s", err, data)
+	}
+	if decoded.Timeout != 3*time.Second {
+		t.Errorf("got %v, want 3s", decoded.Timeout)
+	}
+}
+
+func TestXMLReplacesDefaults(t *testing.T) {
+	cfg := struct {
+		L      []int               `xml:"l"`
+		Labels map[string][]string `xml:"labels"`
+	}{
+		L:      []int{9},
+		Labels: map[string][]string{"env": {"dev"}},
+	}
+	input := []byte(`<config><l>1</l><l>2</l><labels><env>prod</env><env>eu</env></labels></config>`)
+	if err := cx.New().Decode(input, &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(cfg.L) != 2 || cfg.L[0] != 1 || cfg.L[1] != 2 {
+		t.Errorf("L: got %v, want [1 2]", cfg.L)
+	}
+	if env := cfg.Labels["env"]; len(env) != 2 || env[0] != "prod" {
+		t.Errorf("Labels: got %v, want [prod eu]", env)
+	}
+}
+
+func TestXMLByteArray(t *testing.T) {
+	type config struct {
+		ID [4]byte `xml:"id"`
+	}
+	data, err := cx.New().Encode(config{ID: [4]byte{'a', 'b', 'c', 'd'}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(string(data), "<id>abcd</id>") {
+		t.Errorf("unexpected output:\n%s", data)
+	}
+}
+
+// point encodes itself as <name x=".." y=".."/>.
+type point struct{ X, Y int }
+
+func (p point) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "x"}, Value: strconv.Itoa(p.X)},
+		xml.Attr{Name: xml.Name{Local: "y"}, Value: strconv.Itoa(p.Y)})
+	return e.EncodeElement("", start)
+}
+
+func (p *point) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		n, err := strconv.Atoi(attr.Value)
+		if err != nil {
+			return err
+		}
+		switch attr.Name.Local {
+		case "x":
+			p.X = n
+		case "y":
+			p.Y = n
+		}
+	}
+	return d.Skip()
+}
+
+func TestXMLMarshaler(t *testing.T) {
+	type config struct {
+		Origin point   `xml:"origin"`
+		Path   []point `xml:"point"`
+	}
+	original := config{Origin: point{1, 2}, Path: []point{{3, 4}, {5, 6}}}
+	data, err := cx.New().Encode(original)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(string(data), `<origin x="1" y="2"></origin>`) {
+		t.Errorf("unexpected output:\n%s", data)
+	}
+	var decoded config
+	if err := cx.New().Decode(data, &decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Origin != original.Origin || len(decoded.Path) != 2 || decoded.Path[1] != original.Path[1] {
+		t.Errorf("got %+v, want %+v", decoded, original)
+	}
+}
+
+func TestXMLNestedNames(t *testing.T) {
+	type config struct {
+		Host string `xml:"db>host"`
+	}
+	if _, err := cx.New().Encode(config{Host: "x"}); err == nil || !strings.Contains(err.Error(), "db>host") {
+		t.Errorf("Encode: expected an error naming db>host, got %v", err)
+	}
+	var cfg config
+	if err := cx.New().Decode([]byte(`<config><db><host>x</host></db></config>`), &cfg); err == nil {
+		t.Error("Decode: expected an error")
+	}
+}
@@ -0,0 +1,404 @@
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuln/conf"
+)
+
+// Edit parses data into a document that can be modified in place.
+// Changed keys are re-rendered on their own lines; all other lines,
+// including comments and blank lines, are preserved byte for byte.
+func (c *yamlCodec) Edit(data []byte) (conf.EditableDocument, error) {
+	d := &document{indent: c.indent}
+	if err := d.reset(data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+var _ conf.Editor = (*yamlCodec)(nil)
+
+type document struct {
+	src    []byte
+	lines  []int // byte offset at which each line starts
+	root   yaml.Node
+	indent int
+}
+
+// pair is a mapping entry reached while walking a path.
+type pair struct {
+	mapping    *yaml.Node
+	key, value *yaml.Node
+}
+
+func (d *document) Bytes() []byte {
+	return d.src
+}
+
+func (d *document) Get(path []string, v any) error {
+	chain, err := d.find(path)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return d.body().Decode(v)
+	}
+	return chain[len(chain)-1].value.Decode(v)
+}
+
+func (d *document) Set(path []string, value any) error {
+	body := d.body()
+	if body == nil {
+		text, err := d.renderMapping(path, value, "")
+		if err != nil {
+			return err
+		}
+		return d.insertLines(len(d.lines), text)
+	}
+
+	var chain []pair
+	cur := body
+	for i, key := range path {
+		if cur.Kind != yaml.MappingNode {
+			if cur.Tag == "!!null" && len(chain) > 0 {
+				// An empty value such as "db:" becomes a new mapping.
+				return d.replace(chain, nest(path[i:], value))
+			}
+			return fmt.Errorf("yaml: cannot set %q: %q is not a mapping",
+				strings.Join(path, "."), strings.Join(path[:i], "."))
+		}
+		idx := findKey(cur, key)
+		if idx < 0 {
+			return d.insert(chain, cur, path[i:], value)
+		}
+		chain = append(chain, pair{mapping: cur, key: cur.Content[idx], value: cur.Content[idx+1]})
+		cur = cur.Content[idx+1]
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("yaml: cannot replace the document root")
+	}
+	return d.replace(chain, value)
+}
+
+func (d *document) Delete(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("yaml: cannot delete the document root")
+	}
+	chain, err := d.find(path)
+	if err != nil {
+		return err
+	}
+	last := chain[len(chain)-1]
+
+	if d.isBlockEntry(last) {
+		start := last.key.Line - 1
+		// The head comment of the first key may be the document's own, so
+		// it is kept for the next key or the document.
+		first := last.mapping == d.body() && findKey(last.mapping, last.key.Value) == 0
+		if last.key.HeadComment != "" && !first {
+			// Remove the comment lines describing the deleted key.
+			for start > 0 && d.isComment(start-1, last.key.Column-1) {
+				start--
+			}
+		}
+		return d.spliceLines(start, d.extentEnd(last), "")
+	}
+
+	idx := findKey(last.mapping, last.key.Value)
+	last.mapping.Content = append(last.mapping.Content[:idx], last.mapping.Content[idx+2:]...)
+	return d.rerender(chain[:len(chain)-1])
+}
+
+// replace sets the value of the last entry in chain.
+func (d *document) replace(chain []pair, value any) error {
+	last := chain[len(chain)-1]
+	node, err := encodeNode(value)
+	if err != nil {
+		return err
+	}
+	if last.value.Kind == yaml.ScalarNode && node.Kind == yaml.ScalarNode {
+		node.LineComment = last.value.LineComment
+	}
+
+	if d.isBlockEntry(last) {
+		text, err := d.renderPair(last.key, node, d.indentAt(last.key))
+		if err != nil {
+			return err
+		}
+		return d.spliceLines(last.key.Line-1, d.extentEnd(last), text)
+	}
+
+	idx := findKey(last.mapping, last.key.Value)
+	last.mapping.Content[idx+1] = node
+	return d.rerender(chain[:len(chain)-1])
+}
+
+// insert adds keys[0] to mapping, nesting the remaining keys.
+// chain leads to mapping and is empty for the document body.
+func (d *document) insert(chain []pair, mapping *yaml.Node, keys []string, value any) error {
+	block := mapping.Style&yaml.FlowStyle == 0 && len(mapping.Content) > 0
+	if block && (len(chain) == 0 || d.isBlockEntry(chain[len(chain)-1])) {
+		first := mapping.Content[0]
+		lastPair := pair{
+			mapping: mapping,
+			key:     mapping.Content[len(mapping.Content)-2],
+			value:   mapping.Content[len(mapping.Content)-1],
+		}
+		text, err := d.renderMapping(keys, value, d.indentAt(first))
+		if err != nil {
+			return err
+		}
+		return d.insertLines(d.extentEnd(lastPair), text)
+	}
+
+	node, err := encodeNode(nest(keys[1:], value))
+	if err != nil {
+		return err
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: keys[0]}, node)
+	return d.rerender(chain)
+}
+
+// rerender writes the modified tree back to the source by re-rendering the
+// innermost entry of chain that starts its own line, or the whole document
+// if there is none.
+func (d *document) rerender(chain []pair) error {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if d.isBlockEntry(chain[i]) {
+			text, err := d.renderPair(chain[i].key, chain[i].value, d.indentAt(chain[i].key))
+			if err != nil {
+				return err
+			}
+			return d.spliceLines(chain[i].key.Line-1, d.extentEnd(chain[i]), text)
+		}
+	}
+	data, err := d.encode(&d.root)
+	if err != nil {
+		return err
+	}
+	return d.reset(data)
+}
+
+// find returns the entries along path.
+func (d *document) find(path []string) ([]pair, error) {
+	cur := d.body()
+	if cur == nil {
+		return nil, fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+	}
+	chain := make([]pair, 0, len(path))
+	for _, key := range path {
+		idx := -1
+		if cur.Kind == yaml.MappingNode {
+			idx = findKey(cur, key)
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s", conf.ErrKeyNotFound, strings.Join(path, "."))
+		}
+		chain = append(chain, pair{mapping: cur, key: cur.Content[idx], value: cur.Content[idx+1]})
+		cur = cur.Content[idx+1]
+		if cur.Kind == yaml.AliasNode {
+			cur = cur.Alias
+		}
+	}
+	return chain, nil
+}
+
+// body returns the top-level node of the document, or nil if it is empty.
+func (d *document) body() *yaml.Node {
+	if len(d.root.Content) == 0 {
+		return nil
+	}
+	return d.root.Content[0]
+}
+
+// isBlockEntry reports whether p belongs to a block mapping and its key
+// starts a line, so that it can be re-rendered line by line.
+func (d *document) isBlockEntry(p pair) bool {
+	return p.mapping.Style&yaml.FlowStyle == 0 && d.indentOf(p.key.Line-1) == p.key.Column-1
+}
+
+// extentEnd returns the line after the last line occupied by p's value.
+func (d *document) extentEnd(p pair) int {
+	keyLine, keyIndent := p.key.Line-1, p.key.Column-1
+	seq := p.value.Kind == yaml.SequenceNode && p.value.Style&yaml.FlowStyle == 0
+	end := keyLine + 1
+	for l := keyLine + 1; l < len(d.lines); l++ {
+		text := bytes.TrimSpace(d.line(l))
+		if len(text) == 0 {
+			continue
+		}
+		indent := d.indentOf(l)
+		// Block sequences may be indented at the level of their key.
+		if indent > keyIndent || seq && indent == keyIndent && text[0] == '-' {
+			end = l + 1
+			continue
+		}
+		break
+	}
+	return end
+}
+
+func (d *document) isComment(l, indent int) bool {
+	return d.indentOf(l) == indent && bytes.HasPrefix(bytes.TrimSpace(d.line(l)), []byte("#"))
+}
+
+func (d *document) line(l int) []byte {
+	end := len(d.src)
+	if l+1 < len(d.lines) {
+		end = d.lines[l+1]
+	}
+	return d.src[d.lines[l]:end]
+}
+
+func (d *document) indentOf(l int) int {
+	text := d.line(l)
+	return len(text) - len(bytes.TrimLeft(text, " "))
+}
+
+func (d *document) indentAt(key *yaml.Node) string {
+	return strings.Repeat(" ", key.Column-1)
+}
+
+// unit returns the indentation step used by the document.
+func (d *document) unit() int {
+	var find func(n *yaml.Node) int
+	find = func(n *yaml.Node) int {
+		if n.Kind != yaml.MappingNode || n.Style&yaml.FlowStyle != 0 {
+			return 0
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v := n.Content[i+1]
+			if v.Kind == yaml.MappingNode && v.Style&yaml.FlowStyle == 0 && len(v.Content) > 0 {
+				if step := v.Content[0].Column - n.Content[i].Column; step > 0 {
+					return step
+				}
+			}
+			if step := find(v); step > 0 {
+				return step
+			}
+		}
+		return 0
+	}
+	if body := d.body(); body != nil {
+		if step := find(body); step > 0 {
+			return step
+		}
+	}
+	return d.indent
+}
+
+// renderMapping renders keys, nested around value, as a block mapping
+// indented by indent.
+func (d *document) renderMapping(keys []string, value any, indent string) (string, error) {
+	node, err := encodeNode(nest(keys[1:], value))
+	if err != nil {
+		return "", err
+	}
+	return d.renderPair(&yaml.Node{Kind: yaml.ScalarNode, Value: keys[0]}, node, indent)
+}
+
+// renderPair renders a single mapping entry indented by indent.
+func (d *document) renderPair(key, value *yaml.Node, indent string) (string, error) {
+	k := *key
+	k.HeadComment, k.FootComment = "", ""
+	v := *value
+	v.HeadComment, v.FootComment = "", ""
+	data, err := d.encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{&k, &v}})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(indent)
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
+func (d *document) encode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(d.unit())
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// insertLines inserts text before line l.
+func (d *document) insertLines(l int, text string) error {
+	return d.spliceLines(l, l, text)
+}
+
+// spliceLines replaces lines [start, end) with text.
+func (d *document) spliceLines(start, end int, text string) error {
+	from, to := len(d.src), len(d.src)
+	if start < len(d.lines) {
+		from = d.lines[start]
+	}
+	if end < len(d.lines) {
+		to = d.lines[end]
+	}
+
+	src := make([]byte, 0, len(d.src)+len(text))
+	src = append(src, d.src[:from]...)
+	if len(src) > 0 && src[len(src)-1] != '\n' {
+		src = append(src, '\n')
+	}
+	src = append(src, text...)
+	src = append(src, d.src[to:]...)
+	return d.reset(src)
+}
+
+// reset replaces the document source and parses it again.
+func (d *document) reset(src []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(src, &root); err != nil {
+		return err
+	}
+	lines := []int{0}
+	for i, c := range src {
+		if c == '\n' && i+1 < len(src) {
+			lines = append(lines, i+1)
+		}
+	}
+	d.src, d.lines, d.root = src, lines, root
+	return nil
+}
+
+// findKey returns the index of key in the content of mapping, or -1.
+func findKey(mapping *yaml.Node, key string) int {
+	for i := len(mapping.Content) - 2; i >= 0; i -= 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func encodeNode(value any) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// nest wraps value in one single-key mapping per key.
+func nest(keys []string, value any) any {
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = map[string]any{keys[i]: value}
+	}
+	return value
+}
@@ -0,0 +1,237 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuln/conf"
+	"github.com/nuln/conf/internal/scalar"
+)
+
+// DecodeNode decodes a single document into a tree that keeps the order of
+// mapping keys and the resolved type of each scalar. Aliases and merge
+// keys are expanded.
+func (c *yamlCodec) DecodeNode(data []byte) (*conf.Node, error) {
+	var root yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&root); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := single(decoder); err != nil {
+		return nil, err
+	}
+	return fromYAML(&root)
+}
+
+// EncodeNode encodes n, quoting strings that would otherwise read back as
+// another type.
+func (c *yamlCodec) EncodeNode(n *conf.Node) ([]byte, error) {
+	node, err := toYAML(n)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encode(node)
+}
+
+var _ conf.NodeCodec = (*yamlCodec)(nil)
+
+// fromYAML converts n, recording its position.
+func fromYAML(n *yaml.Node) (*conf.Node, error) {
+	out, err := convert(n)
+	if err != nil {
+		return nil, err
+	}
+	if n.Kind != yaml.DocumentNode {
+		out.Line, out.Column = n.Line, n.Column
+	}
+	return out, nil
+}
+
+func convert(n *yaml.Node) (*conf.Node, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return &conf.Node{Kind: conf.NullNode}, nil
+		}
+		return fromYAML(n.Content[0])
+	case yaml.AliasNode:
+		return fromYAML(n.Alias)
+	case yaml.MappingNode:
+		out := &conf.Node{Kind: conf.MapNode, Fields: []conf.Field{}}
+		var merged []conf.Field
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.ShortTag() == "!!merge" {
+				fields, err := mergeFields(value)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, fields...)
+				continue
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("yaml: line %d: unsupported non-scalar mapping key", key.Line)
+			}
+			v, err := fromYAML(value)
+			if err != nil {
+				return nil, err
+			}
+			out.Fields = append(out.Fields, conf.Field{Key: key.Value, Value: v})
+		}
+		// Keys given explicitly take precedence over merged ones.
+		for _, f := range merged {
+			if out.Get(f.Key) == nil {
+				out.Fields = append(out.Fields, f)
+			}
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := &conf.Node{Kind: conf.ListNode, Items: make([]*conf.Node, len(n.Content))}
+		for i, item := range n.Content {
+			v, err := fromYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			out.Items[i] = v
+		}
+		return out, nil
+	}
+
+	switch n.ShortTag() {
+	case "!!null":
+		return &conf.Node{Kind: conf.NullNode}, nil
+	case "!!bool":
+		var b bool
+		err := n.Decode(&b)
+		return &conf.Node{Kind: conf.BoolNode, Value: b}, err
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err == nil {
+			return &conf.Node{Kind: conf.IntNode, Value: i}, nil
+		}
+		var f float64
+		err := n.Decode(&f)
+		return &conf.Node{Kind: conf.FloatNode, Value: f}, err
+	case "!!float":
+		var f float64
+		err := n.Decode(&f)
+		return &conf.Node{Kind: conf.FloatNode, Value: f}, err
+	case "!!timestamp":
+		// Keep dates without a time of day as dates.
+		if t, err := time.ParseInLocation("2006-1-2", n.Value, scalar.LocalDate); err == nil {
+			return &conf.Node{Kind: conf.TimeNode, Value: t}, nil
+		}
+		var t time.Time
+		err := n.Decode(&t)
+		return &conf.Node{Kind: conf.TimeNode, Value: t}, err
+	case "!!binary":
+		var b []byte
+		err := n.Decode(&b)
+		return &conf.Node{Kind: conf.BytesNode, Value: b}, err
+	}
+	return &conf.Node{Kind: conf.StringNode, Value: n.Value}, nil
+}
+
+// mergeFields returns the fields merged in by a "<<" key, whose value is a
+// mapping or a sequence of mappings in decreasing order of precedence.
+func mergeFields(n *yaml.Node) ([]conf.Field, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.SequenceNode {
+		out := &conf.Node{Kind: conf.MapNode}
+		for _, item := range n.Content {
+			fields, err := mergeFields(item)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range fields {
+				if out.Get(f.Key) == nil {
+					out.Fields = append(out.Fields, f)
+				}
+			}
+		}
+		return out.Fields, nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("yaml: line %d: merge value must be a mapping", n.Line)
+	}
+	m, err := fromYAML(n)
+	if err != nil {
+		return nil, err
+	}
+	return m.Fields, nil
+}
+
+func toYAML(n *conf.Node) (*yaml.Node, error) {
+	if n == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	switch n.Kind {
+	case conf.MapNode:
+		out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, f := range n.Fields {
+			v, err := toYAML(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.Key}, v)
+		}
+		return out, nil
+	case conf.ListNode:
+		out := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range n.Items {
+			v, err := toYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, v)
+		}
+		return out, nil
+	}
+
+	out := &yaml.Node{Kind: yaml.ScalarNode}
+	switch v := n.Value.(type) {
+	case nil:
+		out.Tag, out.Value = "!!null", "null"
+	case bool:
+		out.Tag, out.Value = "!!bool", strconv.FormatBool(v)
+	case int64:
+		out.Tag, out.Value = "!!int", strconv.FormatInt(v, 10)
+	case float64:
+		out.Tag = "!!float"
+		switch {
+		case math.IsNaN(v):
+			out.Value = ".nan"
+		case math.IsInf(v, 1):
+			out.Value = ".inf"
+		case math.IsInf(v, -1):
+			out.Value = "-.inf"
+		default:
+			out.Value = scalar.FormatFloat(v)
+		}
+	case string:
+		out.Tag, out.Value = "!!str", v
+	case time.Time:
+		out.Tag, out.Value = "!!timestamp", scalar.FormatTime(v)
+		switch v.Location().String() {
+		case "time-local", "datetime-local":
+			// YAML has no timestamps without a date or without an
+			// offset written this way, so these stay plain strings.
+			out.Tag = "!!str"
+		}
+	case []byte:
+		out.Tag, out.Value = "!!binary", base64.StdEncoding.EncodeToString(v)
+	default:
+		return nil, fmt.Errorf("yaml: unsupported %s node value %T", n.Kind, n.Value)
+	}
+	return out, nil
+}
@@ -5,6 +5,11 @@
 package yaml
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+
 	"gopkg.in/yaml.v3"
 
 	"github.com/nuln/conf"
@@ -14,23 +19,121 @@ func init() {
 	conf.Register("yaml", New())
 }
 
-// New returns a new YAML codec.
-func New() conf.Codec {
-	return &yamlCodec{}
+// Option configures a YAML codec created by New.
+type Option func(*yamlCodec)
+
+// Indent sets the number of spaces used for each indentation level when
+// encoding. The default is four.
+func Indent(spaces int) Option {
+	return func(c *yamlCodec) {
+		c.indent = spaces
+	}
+}
+
+// New returns a new YAML codec configured with opts.
+func New(opts ...Option) conf.Codec {
+	c := &yamlCodec{indent: 4}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-type yamlCodec struct{}
+type yamlCodec struct {
+	indent int
+}
 
 func (c *yamlCodec) Encode(v any) ([]byte, error) {
-	return yaml.Marshal(v)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(c.indent)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
+// Decode decodes a single document into v. Use conf.LoadAll to read a
+// stream of several documents.
 func (c *yamlCodec) Decode(data []byte, v any) error {
-	return yaml.Unmarshal(data, v)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+	return single(decoder)
+}
+
+// empty reports whether n is the implicit null of an empty document.
+func empty(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null" && n.Value == ""
+}
+
+// single returns an error if decoder has non-empty documents left.
+func single(decoder *yaml.Decoder) error {
+	for {
+		var next yaml.Node
+		switch err := decoder.Decode(&next); {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+		if len(next.Content) > 0 && !empty(next.Content[0]) {
+			return errors.New("yaml: data holds more than one document; use conf.LoadAll to read them")
+		}
+	}
+}
+
+// DecodeAll calls fn for each document in a "---" separated stream.
+func (c *yamlCodec) DecodeAll(data []byte, fn func(decode func(v any) error) error) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := fn(doc.Decode); err != nil {
+			return err
+		}
+	}
+}
+
+// yamlStart matches the first significant line of a block-style YAML
+// document: a document marker, a directive, a sequence item or a key.
+var yamlStart = regexp.MustCompile(`^(---|%YAML|- |-$|[^\s#{\[][^:#]*:(\s|$))`)
+
+// Sniff reports whether data looks like a block-style YAML document.
+// Flow-style documents are left to the JSON codec.
+func (c *yamlCodec) Sniff(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimRight(line, " \t\r")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if !yamlStart.Match(line) {
+			return false
+		}
+		var v any
+		return yaml.Unmarshal(data, &v) == nil
+	}
+	return false
 }
 
 func (c *yamlCodec) Extensions() []string {
 	return []string{".yaml", ".yml"}
 }
 
-var _ conf.Codec = (*yamlCodec)(nil)
+var (
+	_ conf.Codec        = (*yamlCodec)(nil)
+	_ conf.MultiDecoder = (*yamlCodec)(nil)
+	_ conf.Sniffer      = (*yamlCodec)(nil)
+)
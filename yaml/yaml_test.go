@@ -1,7 +1,12 @@
 package yaml_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 
 	"github.com/nuln/conf"
 	"github.com/nuln/conf/conftest"
@@ -25,3 +30,170 @@ func TestYAMLRegistration(t *testing.T) {
 		t.Error("yaml should be registered via init()")
 	}
 }
+
+func TestYAMLIndent(t *testing.T) {
+	v := map[string]any{"db": map[string]any{"host": "localhost"}}
+
+	data, err := cy.New(cy.Indent(2)).Encode(v)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "db:\n  host: localhost\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestYAMLEdit(t *testing.T) {
+	input := `# service settings
+name: billing # display name
+
+database:
+  host: localhost
+
+  # connection pool
+  pool: 10
+`
+	want := `# service settings
+name: payments # display name
+
+database:
+  host: localhost
+  user: admin
+tags:
+  - web
+
+`
+
+	doc, err := cy.New().(conf.Editor).Edit([]byte(input))
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if err := doc.Set([]string{"name"}, "payments"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Delete([]string{"database", "pool"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := doc.Set([]string{"database", "user"}, "admin"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Set([]string{"tags"}, []string{"web"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", got, want)
+	}
+
+	var host string
+	if err := doc.Get([]string{"database", "host"}, &host); err != nil || host != "localhost" {
+		t.Errorf("Get: got %q, %v", host, err)
+	}
+}
+
+func TestYAMLEditDeleteFirstKey(t *testing.T) {
+	doc, err := cy.New().(conf.Editor).Edit([]byte("# service settings\nname: billing\nport: 80\n"))
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if err := doc.Delete([]string{"name"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if got, want := string(doc.Bytes()), "# service settings\nport: 80\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestYAMLDecodeAll(t *testing.T) {
+	data := []byte("kind: Service\nname: web\n---\nkind: Deployment\nname: web\n---\nkind: Secret\n")
+
+	var kinds []string
+	err := conf.LoadAllFromBytes(data, "yaml", func(i int, decode func(v any) error) error {
+		var doc struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := decode(&doc); err != nil {
+			return err
+		}
+		if len(kinds) != i {
+			t.Errorf("document index: got %d, want %d", i, len(kinds))
+		}
+		kinds = append(kinds, doc.Kind)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("LoadAllFromBytes failed: %v", err)
+	}
+	if len(kinds) != 3 || kinds[2] != "Secret" {
+		t.Errorf("got %v", kinds)
+	}
+}
+
+func TestYAMLDecodeMultipleDocuments(t *testing.T) {
+	var v map[string]any
+	err := conf.LoadFromBytes([]byte("a: 1\n---\nb: 2\n"), "yaml", &v)
+	if err == nil || !strings.Contains(err.Error(), "LoadAll") {
+		t.Errorf("expected an error pointing to LoadAll, got: %v", err)
+	}
+
+	v = nil
+	if err := conf.LoadFromBytes([]byte("---\na: 1\n---\n"), "yaml", &v); err != nil {
+		t.Errorf("trailing empty document: %v", err)
+	}
+	if v["a"] != 1 {
+		t.Errorf("got %v", v)
+	}
+}
+
+func TestYAMLNode(t *testing.T) {
+	src := "defaults: &defaults\n    port: 80\n    tls: false\nweb:\n    <<: *defaults\n    port: 8080\n    ratio: 1.0\n    code: \"007\"\n"
+
+	nc := cy.New().(conf.NodeCodec)
+	n, err := nc.DecodeNode([]byte(src))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	web := n.Get("web")
+	if port := web.Get("port"); port.Kind != conf.IntNode || port.Value != int64(8080) {
+		t.Errorf("web.port: got %s %v, want 8080", port.Kind, port.Value)
+	}
+	if tls := web.Get("tls"); tls == nil || tls.Value != false {
+		t.Errorf("web.tls not merged: %v", tls)
+	}
+
+	data, err := nc.EncodeNode(web)
+	if err != nil {
+		t.Fatalf("EncodeNode failed: %v", err)
+	}
+	if want := "port: 8080\nratio: 1.0\ncode: \"007\"\ntls: false\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+// level decodes itself from YAML names only.
+type level int
+
+func (l *level) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Value {
+	case "info":
+		*l = 1
+	case "debug":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", n.Value)
+	}
+	return nil
+}
+
+func TestYAMLUnmarshalerWithDecodeHook(t *testing.T) {
+	var cfg struct {
+		Level   level         `yaml:"level"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	if err := conf.LoadFromBytes([]byte("level: debug\ntimeout: 30s\n"), "yaml", &cfg); err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	if cfg.Level != 2 || cfg.Timeout != 30*time.Second {
+		t.Errorf("got %+v", cfg)
+	}
+}